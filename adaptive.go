@@ -0,0 +1,116 @@
+package wave
+
+import "sync"
+
+const (
+	adaptiveWindow   = 100 // Most recent items the error rate is measured over
+	adaptiveInterval = 50  // Items between adjustments of a Once wave
+)
+
+// adaptiveConcurrency resizes the worker pool of a wave based on its recent
+// error rate.
+type adaptiveConcurrency struct {
+	enabled   bool
+	min, max  int
+	target    float64
+	current   int
+	window    [adaptiveWindow]bool // Ring of recent outcomes, true for errors
+	n, next   int                  // Outcomes in window, and where the next goes
+	sinceLast int                  // Items since the last adjustment
+	lock      sync.Mutex           // Guards all fields
+}
+
+// SetAdaptiveConcurrency lets the wave tune its own concurrency between min
+// and max. The error rate is measured over the last 100 items since the
+// concurrency last changed: when it is above targetErrorRate, concurrency is
+// lowered by one, and when it is below half of it, concurrency is raised by
+// one. Continuous waves adjust after every wave, other waves after every 50
+// items. The concurrency the wave was created with is the starting point,
+// clamped to min and max.
+func (h *Handle) SetAdaptiveConcurrency(min, max int, targetErrorRate float64) {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	current := h.concurrency
+	if current > max {
+		current = max
+	}
+	if current < min {
+		current = min
+	}
+	a := &h.adaptive
+	a.lock.Lock()
+	a.enabled, a.min, a.max, a.target, a.current = true, min, max, targetErrorRate, current
+	a.n, a.next, a.sinceLast = 0, 0, 0
+	a.lock.Unlock()
+	if pool := h.pool.Load(); pool != nil {
+		pool.Resize(current)
+	}
+}
+
+// CurrentConcurrency returns the number of workers the wave is using, which
+// changes over time with SetAdaptiveConcurrency.
+func (h *Handle) CurrentConcurrency() int {
+	a := &h.adaptive
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.enabled {
+		return a.current
+	}
+	return h.concurrency
+}
+
+// recordOutcome adds an item to the error rate window, and adjusts the
+// concurrency of Once waves every adaptiveInterval items.
+func (h *Handle) recordOutcome(err error) {
+	a := &h.adaptive
+	a.lock.Lock()
+	if !a.enabled {
+		a.lock.Unlock()
+		return
+	}
+	a.window[a.next] = err != nil
+	a.next = (a.next + 1) % adaptiveWindow
+	a.n = min(a.n+1, adaptiveWindow)
+	a.sinceLast++
+	adjust := !h.continuous && a.sinceLast >= adaptiveInterval
+	a.lock.Unlock()
+	if adjust {
+		h.adjustConcurrency()
+	}
+}
+
+// adjustConcurrency resizes the pool according to the current error rate.
+// Continuous waves call it after every wave.
+func (h *Handle) adjustConcurrency() {
+	a := &h.adaptive
+	a.lock.Lock()
+	if !a.enabled || a.n == 0 {
+		a.lock.Unlock()
+		return
+	}
+	errs := 0
+	for i := 0; i < a.n; i++ {
+		if a.window[i] {
+			errs++
+		}
+	}
+	rate := float64(errs) / float64(a.n)
+	switch {
+	case rate > a.target && a.current > a.min:
+		a.current--
+		a.n, a.next = 0, 0
+	case rate < a.target/2 && a.current < a.max:
+		a.current++
+		a.n, a.next = 0, 0
+	}
+	a.sinceLast = 0
+	current := a.current
+	a.lock.Unlock()
+	if pool := h.pool.Load(); pool != nil {
+		pool.Resize(current)
+	}
+}
@@ -0,0 +1,65 @@
+package wave
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// overloaded returns a callback that fails whenever more than limit items run
+// at once, like a service that sheds load.
+func overloaded(limit int64) func(string) error {
+	var running atomic.Int64
+	return func(string) error {
+		defer running.Add(-1)
+		if running.Add(1) > limit {
+			return errFault
+		}
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}
+}
+
+func TestAdaptiveConcurrencyContinuous(t *testing.T) {
+	var w *Handle
+	w = continuous(10, func() Source { return SliceSource(FakeEndpoints()) }, overloaded(3))
+	w.SetAdaptiveConcurrency(1, 10, 0.1)
+	w.AfterEach(func() {
+		if w.waves.Load() == 30 {
+			go w.Finish()
+		}
+	})
+	if n := w.CurrentConcurrency(); n != 10 {
+		t.Error("Expected to start at 10, got", n)
+	}
+	w.Start()
+	w.Wait()
+
+	// Concurrency oscillates around the load limit of 3.
+	if n := w.CurrentConcurrency(); n < 2 || n > 5 {
+		t.Error("Expected concurrency to settle near 3, got", n)
+	}
+}
+
+func TestAdaptiveConcurrencyOnce(t *testing.T) {
+	vals := make([]string, 20*adaptiveInterval)
+	w := once(8, SliceSource(vals), overloaded(2))
+	w.SetAdaptiveConcurrency(2, 8, 0.05)
+	w.Finish()
+
+	if n := w.CurrentConcurrency(); n >= 8 {
+		t.Error("Expected concurrency to be lowered from 8, got", n)
+	}
+}
+
+func TestAdaptiveConcurrencyBounds(t *testing.T) {
+	w := once(20, SliceSource(FakeEndpoints()), func(string) error { return nil })
+	if n := w.CurrentConcurrency(); n != 20 {
+		t.Error("Expected 20 without adaptive concurrency, got", n)
+	}
+	w.SetAdaptiveConcurrency(2, 5, 0.1)
+	if n := w.CurrentConcurrency(); n != 5 {
+		t.Error("Expected the starting concurrency to be clamped to 5, got", n)
+	}
+	w.Finish()
+}
@@ -0,0 +1,117 @@
+package wave
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BenchmarkReport is the result of Benchmark.
+type BenchmarkReport struct {
+	Runs            []BenchmarkRun
+	BestConcurrency int // Concurrency of the run with the highest throughput
+}
+
+// BenchmarkRun describes one wave run by Benchmark.
+type BenchmarkRun struct {
+	Concurrency    int
+	Items          int // Items processed, less than len(vals) if cancelled
+	Errors         int
+	Duration       time.Duration
+	ItemsPerSecond float64
+	ErrorRate      float64 // Errors / Items
+}
+
+// Benchmark runs one wave over vals for every concurrency level in turn and
+// measures its throughput and error rate, to help pick a concurrency for a
+// callback. Cancelling ctx interrupts the current wave and skips the
+// remaining ones.
+func Benchmark(ctx context.Context, vals []string, callback func(string) error, concurrencies []int) BenchmarkReport {
+	report := BenchmarkReport{}
+	best := 0.0
+	for _, concurrency := range concurrencies {
+		if ctx.Err() != nil {
+			break
+		}
+		var items, errors atomic.Int64
+		h := once(concurrency, SliceSource(vals), func(val string) error {
+			err := callback(val)
+			if err != nil {
+				errors.Add(1)
+			}
+			items.Add(1)
+			return err
+		})
+
+		began := time.Now()
+		h.Start()
+		select {
+		case <-h.stopChan:
+		case <-ctx.Done():
+			h.Interrupt()
+		}
+		run := BenchmarkRun{
+			Concurrency: concurrency,
+			Items:       int(items.Load()),
+			Errors:      int(errors.Load()),
+			Duration:    time.Since(began),
+		}
+		if run.Duration > 0 {
+			run.ItemsPerSecond = float64(run.Items) / run.Duration.Seconds()
+		}
+		if run.Items > 0 {
+			run.ErrorRate = float64(run.Errors) / float64(run.Items)
+		}
+		if run.ItemsPerSecond > best {
+			best = run.ItemsPerSecond
+			report.BestConcurrency = concurrency
+		}
+		report.Runs = append(report.Runs, run)
+	}
+	return report
+}
+
+// BenchmarkResult is the result of BenchmarkCallback.
+type BenchmarkResult struct {
+	ItemsPerSecond float64
+	TotalDuration  time.Duration
+	P50            time.Duration // Median duration of the callback
+	P99            time.Duration
+}
+
+// BenchmarkCallback runs one wave of itemCount synthetic items, "item-0",
+// "item-1" and so on, and measures the throughput of callback and the
+// distribution of its durations. Unlike testing.B, it can be used to
+// benchmark a callback from production code.
+func BenchmarkCallback(concurrency int, itemCount int, callback func(string)) BenchmarkResult {
+	vals := make([]string, itemCount)
+	for i := range vals {
+		vals[i] = "item-" + strconv.Itoa(i)
+	}
+	durations := make([]time.Duration, 0, itemCount)
+	lock := sync.Mutex{} // Guards durations
+	h := Once(concurrency, vals, func(val string) {
+		began := time.Now()
+		callback(val)
+		d := time.Since(began)
+		lock.Lock()
+		durations = append(durations, d)
+		lock.Unlock()
+	})
+
+	began := time.Now()
+	h.Finish()
+	result := BenchmarkResult{TotalDuration: time.Since(began)}
+	if result.TotalDuration > 0 {
+		result.ItemsPerSecond = float64(len(durations)) / result.TotalDuration.Seconds()
+	}
+	if len(durations) > 0 {
+		slices.Sort(durations)
+		result.P50 = durations[len(durations)*50/100]
+		result.P99 = durations[len(durations)*99/100]
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package wave
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBenchmark(t *testing.T) {
+	report := Benchmark(context.Background(), FakeEndpoints(), func(host string) error {
+		time.Sleep(2 * time.Millisecond)
+		if host == ":3000" {
+			return errFault
+		}
+		return nil
+	}, []int{1, 10})
+
+	if len(report.Runs) != 2 {
+		t.Fatal("Expected 2 runs, got", len(report.Runs))
+	}
+	if report.BestConcurrency != 10 {
+		t.Error("Expected best concurrency 10, got", report.BestConcurrency)
+	}
+	for _, run := range report.Runs {
+		if run.Items != numPorts || run.Errors != 1 || run.ErrorRate != 0.1 {
+			t.Errorf("Unexpected run %+v", run)
+		}
+	}
+}
+
+func TestBenchmarkCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	report := Benchmark(ctx, FakeEndpoints(), func(string) error {
+		cancel()
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}, []int{1, 2, 3})
+
+	if len(report.Runs) != 1 || report.Runs[0].Items >= numPorts {
+		t.Errorf("Expected one interrupted run, got %+v", report.Runs)
+	}
+}
+
+func TestBenchmarkCallback(t *testing.T) {
+	result := BenchmarkCallback(4, 100, func(val string) {
+		if strings.HasSuffix(val, "7") {
+			time.Sleep(2 * time.Millisecond)
+		}
+	})
+
+	if result.ItemsPerSecond <= 0 || result.TotalDuration <= 0 || result.P50 <= 0 {
+		t.Errorf("Expected positive results, got %+v", result)
+	}
+	if result.P99 < result.P50 || result.P99 < 2*time.Millisecond {
+		t.Errorf("Expected P99 to cover the slow items, got %+v", result)
+	}
+}
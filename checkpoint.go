@@ -0,0 +1,166 @@
+package wave
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// checkpointVersion is written before every checkpoint so that the format can
+// change without misreading old checkpoints.
+const checkpointVersion = 1
+
+// checkpointData is the gob-encoded body of a checkpoint.
+type checkpointData struct {
+	First bool     // Starts a new wave, so earlier checkpoints no longer apply
+	Items []string // Processed successfully since the previous checkpoint
+}
+
+// checkpointer records the items processed by the current wave.
+type checkpointer struct {
+	w        io.Writer
+	interval int
+	done     []string        // Processed successfully since the last checkpoint
+	first    bool            // No checkpoint has been written for the current wave
+	resume   map[string]bool // Loaded by LoadCheckpoint, skipped by the next wave
+	err      error           // First write error
+	lock     sync.Mutex      // Guards all fields
+}
+
+// SetCheckpoint makes the wave write a checkpoint to w every interval items
+// processed successfully, and once more when a wave ends. Each checkpoint
+// only lists the items since the previous one, so writing checkpoints costs
+// the same throughout a long wave. Items whose callback chain failed are left
+// out, so that a resumed wave retries them. A Continuous wave starts over with
+// every wave. Checkpoints are appended to w one after another; LoadCheckpoint
+// reads them all. Write errors are reported by CheckpointErr.
+func (h *Handle) SetCheckpoint(w io.Writer, interval int) {
+	if interval < 1 {
+		interval = 1
+	}
+	h.checkpoint.lock.Lock()
+	h.checkpoint.w, h.checkpoint.interval = w, interval
+	h.checkpoint.lock.Unlock()
+}
+
+// LoadCheckpoint reads checkpoints written by SetCheckpoint from r, for
+// example after a crash, and makes the next wave skip the items that the last
+// wave recorded in r processed successfully. It should be called before Start.
+func (h *Handle) LoadCheckpoint(r io.Reader) error {
+	br := bufio.NewReader(r)
+	var resume map[string]bool
+	for {
+		version, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if version != checkpointVersion {
+			return fmt.Errorf("wave: unsupported checkpoint version %d", version)
+		}
+		data := &checkpointData{}
+		if err := gob.NewDecoder(br).Decode(data); err != nil {
+			return err
+		}
+		if data.First || resume == nil {
+			resume = map[string]bool{}
+		}
+		for _, val := range data.Items {
+			resume[val] = true
+		}
+	}
+	if resume == nil {
+		return errors.New("wave: no checkpoint found")
+	}
+	h.checkpoint.lock.Lock()
+	h.checkpoint.resume = resume
+	h.checkpoint.lock.Unlock()
+	return nil
+}
+
+// CheckpointErr returns the first error returned by the checkpoint writer, if
+// any.
+func (h *Handle) CheckpointErr() error {
+	h.checkpoint.lock.Lock()
+	defer h.checkpoint.lock.Unlock()
+	return h.checkpoint.err
+}
+
+// startCheckpoint resets the checkpoint for a new wave and wraps src to skip
+// the items of a loaded checkpoint, which count as already done.
+func (h *Handle) startCheckpoint(src Source) Source {
+	c := &h.checkpoint
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.done, c.first = nil, true
+	if c.resume == nil {
+		return src
+	}
+	resume := c.resume
+	c.resume = nil
+	for val := range resume {
+		c.done = append(c.done, val)
+	}
+	return &resumeSource{src: src, done: resume}
+}
+
+// checkpointItem records that val was processed successfully and writes a
+// checkpoint if the interval has been reached.
+func (h *Handle) checkpointItem(val string) {
+	c := &h.checkpoint
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.w == nil {
+		return
+	}
+	c.done = append(c.done, val)
+	if len(c.done) >= c.interval {
+		c.write()
+	}
+}
+
+// flushCheckpoint writes a checkpoint if items were processed since the last
+// one.
+func (h *Handle) flushCheckpoint() {
+	c := &h.checkpoint
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.w != nil && len(c.done) > 0 {
+		c.write()
+	}
+}
+
+func (c *checkpointer) write() {
+	data := checkpointData{First: c.first, Items: c.done}
+	c.done, c.first = nil, false
+	_, err := c.w.Write([]byte{checkpointVersion})
+	if err == nil {
+		err = gob.NewEncoder(c.w).Encode(data)
+	}
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+// resumeSource skips items that were processed before a checkpoint.
+type resumeSource struct {
+	src  Source
+	done map[string]bool
+}
+
+func (s *resumeSource) Next() (string, bool) {
+	for {
+		val, ok := s.src.Next()
+		if !ok || !s.done[val] {
+			return val, ok
+		}
+		skipped(s.src, val)
+	}
+}
+
+func (s *resumeSource) skip(val string) { skipped(s.src, val) }
@@ -0,0 +1,145 @@
+package wave
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var first atomic.Int64
+	var w *Handle
+	w = Once(1, FakeEndpoints(), func(string) {
+		if first.Add(1) == 5 {
+			go w.Interrupt() // Crash mid-wave
+			<-w.interruptChan
+		}
+	})
+	w.SetCheckpoint(buf, 2)
+	w.Start()
+	w.Wait()
+	if n := first.Load(); n != 5 {
+		t.Fatal("Expected the wave to stop after 5 items, but it processed", n)
+	}
+
+	var lock sync.Mutex
+	seen := map[string]bool{}
+	resumed := Once(1, FakeEndpoints(), func(val string) {
+		lock.Lock()
+		seen[val] = true
+		lock.Unlock()
+	})
+	if err := resumed.LoadCheckpoint(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	resumed.Finish()
+
+	if total := first.Load() + resumed.processed.Load(); total != numPorts {
+		t.Error("Expected", numPorts, "items processed in total, got", total)
+	}
+	if len(seen) != numPorts-int(first.Load()) {
+		t.Error("Expected the resumed wave to process", numPorts-first.Load(), "distinct items, got", len(seen))
+	}
+	if err := w.CheckpointErr(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckpointInterval(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := Once(2, FakeEndpoints(), func(string) {})
+	w.SetCheckpoint(buf, 4)
+	w.Finish()
+
+	// 10 items make checkpoints after 4, 8 and the end of the wave.
+	if n := bytes.Count(buf.Bytes(), []byte{checkpointVersion}); n < 3 {
+		t.Error("Expected at least 3 checkpoints, found", n, "version bytes")
+	}
+	resumed := Once(1, FakeEndpoints(), func(string) {
+		t.Error("Expected every item to be skipped")
+	})
+	if err := resumed.LoadCheckpoint(buf); err != nil {
+		t.Fatal(err)
+	}
+	resumed.Finish()
+}
+
+func TestLoadCheckpointErrors(t *testing.T) {
+	w := Once(1, FakeEndpoints(), func(string) {})
+	if err := w.LoadCheckpoint(&bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an empty checkpoint")
+	}
+	if err := w.LoadCheckpoint(bytes.NewReader([]byte{checkpointVersion + 1})); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+	if err := w.LoadCheckpoint(bytes.NewReader([]byte{checkpointVersion, 0xff})); err == nil {
+		t.Error("Expected an error for a corrupt checkpoint")
+	}
+	w.Finish()
+}
+
+func TestCheckpointSkipsFailedItems(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := once(1, SliceSource(FakeEndpoints()), func(val string) error {
+		if val == ":3004" {
+			return errFault
+		}
+		return nil
+	})
+	w.SetCheckpoint(buf, 3)
+	w.Finish()
+
+	var retried []string
+	resumed := Once(1, FakeEndpoints(), record(&retried))
+	if err := resumed.LoadCheckpoint(buf); err != nil {
+		t.Fatal(err)
+	}
+	resumed.Finish()
+	if len(retried) != 1 || retried[0] != ":3004" {
+		t.Error("Expected only the failed item to be retried, got", retried)
+	}
+}
+
+func TestCheckpointIncremental(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithMaxIterations(2))
+	w.SetCheckpoint(buf, 2)
+	w.Start()
+	w.Wait()
+
+	// Every checkpoint lists at most interval items, and each wave starts
+	// with a checkpoint marked First.
+	r := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+	var firsts, items int
+	for {
+		if _, err := r.ReadByte(); err != nil {
+			break
+		}
+		var data checkpointData
+		if err := gob.NewDecoder(r).Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Items) > 2 {
+			t.Error("Expected at most 2 items per checkpoint, got", data.Items)
+		}
+		if data.First {
+			firsts++
+		}
+		items += len(data.Items)
+	}
+	if firsts != 2 || items != 2*numPorts {
+		t.Error("Expected 2 waves of", numPorts, "items, got", firsts, "waves and", items, "items")
+	}
+
+	resumed := Once(1, FakeEndpoints(), func(string) {
+		t.Error("Expected every item of the last wave to be skipped")
+	})
+	if err := resumed.LoadCheckpoint(buf); err != nil {
+		t.Fatal(err)
+	}
+	resumed.Finish()
+}
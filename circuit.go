@@ -0,0 +1,70 @@
+package wave
+
+import "time"
+
+// waveCircuitBreaker suspends the waves of a handle whose error rate is too
+// high, see WithWaveCircuitBreaker.
+type waveCircuitBreaker struct {
+	threshold     float64
+	halfOpenAfter time.Duration
+	openUntil     time.Time // Zero while closed, guarded by stateLock of the handle
+}
+
+// WithWaveCircuitBreaker protects the downstream system as a whole by
+// suspending the wave when more than threshold of the items of a wave fail,
+// for example 0.5 for half of them. The circuit then opens: further waves are
+// skipped for halfOpenAfter, after which a single probe wave runs. If the
+// error rate of the probe is at most threshold the circuit closes again,
+// otherwise it stays open for another halfOpenAfter. A Continuous wave waits
+// for the circuit instead of skipping waves, and can be interrupted or
+// finished while it waits.
+func WithWaveCircuitBreaker(threshold float64, halfOpenAfter time.Duration) Option {
+	return func(c *handleConfig) {
+		c.circuit = &waveCircuitBreaker{threshold: threshold, halfOpenAfter: halfOpenAfter}
+	}
+}
+
+// CircuitOpen reports whether the circuit set by WithWaveCircuitBreaker is
+// open, so that waves are being skipped.
+func (h *Handle) CircuitOpen() bool {
+	h.stateLock.RLock()
+	defer h.stateLock.RUnlock()
+	return h.circuit != nil && !h.circuit.openUntil.IsZero()
+}
+
+// circuitAllows reports whether the next wave may run.
+func (h *Handle) circuitAllows() bool {
+	h.stateLock.RLock()
+	defer h.stateLock.RUnlock()
+	return h.circuit == nil || !time.Now().Before(h.circuit.openUntil)
+}
+
+// waitForCircuit waits until the next wave may run. It returns false if h
+// was interrupted or finished first.
+func (h *Handle) waitForCircuit() bool {
+	h.stateLock.RLock()
+	var wait time.Duration
+	if h.circuit != nil {
+		wait = time.Until(h.circuit.openUntil)
+	}
+	h.stateLock.RUnlock()
+	return h.sleep(wait)
+}
+
+// updateCircuit opens or closes the circuit according to the error rate of
+// the wave that just ended.
+func (h *Handle) updateCircuit() {
+	total := h.waveProcessed.Load()
+	failed := len(h.Errors())
+	h.stateLock.Lock()
+	defer h.stateLock.Unlock()
+	c := h.circuit
+	if c == nil || total == 0 {
+		return
+	}
+	if float64(failed)/float64(total) > c.threshold {
+		c.openUntil = time.Now().Add(c.halfOpenAfter)
+	} else {
+		c.openUntil = time.Time{}
+	}
+}
@@ -0,0 +1,69 @@
+package wave
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaveCircuitBreakerOpens(t *testing.T) {
+	halfOpenAfter := 30 * time.Millisecond
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithMaxIterations(3), WithWaveCircuitBreaker(0.5, halfOpenAfter))
+	w.Use(func(func(string) error) func(string) error {
+		return func(string) error { return errFault }
+	})
+	began := time.Now()
+	w.Start()
+	if !w.waitTimeout(5 * time.Second) {
+		w.Interrupt()
+		t.Fatal("Expected the wave to finish")
+	}
+	if d := time.Since(began); d < 2*halfOpenAfter {
+		t.Error("Expected the circuit to suspend the wave twice, took", d)
+	}
+	if !w.CircuitOpen() {
+		t.Error("Expected the failed probe to leave the circuit open")
+	}
+}
+
+func TestWaveCircuitBreakerCloses(t *testing.T) {
+	var w *Handle
+	w = ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithMaxIterations(4), WithWaveCircuitBreaker(0.5, 10*time.Millisecond))
+	w.Use(func(next func(string) error) func(string) error {
+		return func(val string) error {
+			if w.waves.Load() == 1 {
+				return errFault
+			}
+			return next(val)
+		}
+	})
+	w.Start()
+	if !w.waitTimeout(5 * time.Second) {
+		w.Interrupt()
+		t.Fatal("Expected the wave to finish")
+	}
+	if w.CircuitOpen() {
+		t.Error("Expected the successful probe to close the circuit")
+	}
+	if n := w.waves.Load(); n != 4 {
+		t.Error("Expected 4 waves, got", n)
+	}
+}
+
+func TestWaveCircuitBreakerInterrupt(t *testing.T) {
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithWaveCircuitBreaker(0, time.Hour))
+	w.Use(func(func(string) error) func(string) error {
+		return func(string) error { return errFault }
+	})
+	w.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for !w.CircuitOpen() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	w.Interrupt()
+	if !w.waitTimeout(time.Second) {
+		t.Fatal("Expected Interrupt to stop a wave waiting for its circuit")
+	}
+	if n := w.waves.Load(); n != 1 {
+		t.Error("Expected no waves while the circuit is open, got", n)
+	}
+}
@@ -0,0 +1,39 @@
+package wave
+
+import (
+	"bytes"
+	"log/slog"
+	"runtime"
+	"strconv"
+)
+
+// WithDebugMode logs every operation on the channels that control the wave,
+// and every item handed to and taken by a worker, to logger at debug level,
+// with the ID of the goroutine involved. It is meant for diagnosing deadlocks
+// and is slow; without it nothing is logged.
+func WithDebugMode(logger *slog.Logger) Option {
+	return func(c *handleConfig) { c.debug = logger }
+}
+
+// debug logs a channel operation if debug mode is on. It takes stateLock, so
+// it must not be called with stateLock held.
+func (h *Handle) debug(op, ch string, args ...any) {
+	if h.debugLog == nil {
+		return
+	}
+	args = append([]any{"op", op, "chan", ch, "goroutine", goroutineID(), "wave_id", h.ID()}, args...)
+	h.debugLog.Debug("wave: "+op+" "+ch, args...)
+}
+
+// goroutineID returns the ID of the calling goroutine, as printed in stack
+// traces.
+func goroutineID() int {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.Atoi(string(buf))
+	return id
+}
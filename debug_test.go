@@ -0,0 +1,34 @@
+package wave
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithDebugMode(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	w := OnceWithOptions(FakeEndpoints(), func(string) {}, WithConcurrency(2), WithDebugMode(logger))
+	w.Finish()
+
+	out := buf.String()
+	for _, want := range []string{
+		"close startChan", "receive startChan", "close finishChan", "close stopChan",
+		"send valChan", "receive valChan", "item=:3009", "goroutine=", "wave_id=" + w.ID(),
+	} {
+		if !strings.Contains(out, want) {
+			t.Error("Expected", want, "in the debug log:\n"+out)
+		}
+	}
+	if n := strings.Count(out, "receive valChan"); n != numPorts {
+		t.Error("Expected", numPorts, "items received, got", n)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	if id := goroutineID(); id <= 0 {
+		t.Error("Expected a positive goroutine ID, got", id)
+	}
+}
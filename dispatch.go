@@ -0,0 +1,213 @@
+package wave
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// WorkerScheduler decides which worker processes each item of a wave. Every
+// worker has its own queue in workers and only takes items from it. Dispatch
+// must send item to exactly one of workers, and may block while that queue is
+// full. workerID is the worker that strict round-robin would pick next.
+type WorkerScheduler interface {
+	Dispatch(item string, workerID int, workers []chan string)
+}
+
+// SetScheduler makes the wave hand its items to workers through s, instead of
+// through a queue shared by all workers. It must be called before Start. Waves
+// with a scheduler keep the concurrency they were created with, so
+// SetAdaptiveConcurrency has no effect, and QueuedCount and InFlightCount
+// report zero.
+func (h *Handle) SetScheduler(s WorkerScheduler) {
+	h.stateLock.Lock()
+	h.scheduler = s
+	h.stateLock.Unlock()
+}
+
+type consistentHashScheduler struct {
+	concurrency int
+}
+
+// ConsistentHashScheduler returns a WorkerScheduler that always sends an item
+// to the same worker, chosen by hashing the item modulo concurrency, so that
+// workers can keep per-item state such as connections. concurrency should be
+// the concurrency of the wave.
+func ConsistentHashScheduler(concurrency int) WorkerScheduler {
+	return consistentHashScheduler{concurrency: concurrency}
+}
+
+func (s consistentHashScheduler) Dispatch(item string, workerID int, workers []chan string) {
+	n := s.concurrency
+	if n < 1 || n > len(workers) {
+		n = len(workers)
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(item))
+	workers[hash.Sum32()%uint32(n)] <- item
+}
+
+// scheduledPool runs a worker per queue and lets a WorkerScheduler choose the
+// queue of each item.
+type scheduledPool struct {
+	scheduler WorkerScheduler
+	queues    []chan string
+	next      int            // Sequence number of the next item, for round-robin
+	pending   sync.WaitGroup // Items submitted and not processed yet
+	done      sync.WaitGroup // Running workers
+	wake      chan struct{}  // Closed by the next SubmitBlocking, nil if no worker waits
+	wakeLock  sync.Mutex     // Guards wake
+}
+
+func newScheduledPool(concurrency int, scheduler WorkerScheduler, callback func(string)) *scheduledPool {
+	p := &scheduledPool{scheduler: scheduler, queues: make([]chan string, max(concurrency, 1))}
+	for i := range p.queues {
+		p.queues[i] = make(chan string, queueCapacity)
+	}
+	for i := range p.queues {
+		p.done.Add(1)
+		go p.work(i, callback)
+	}
+	return p
+}
+
+func (p *scheduledPool) work(i int, callback func(string)) {
+	defer p.done.Done()
+	own := p.queues[i]
+	s, ok := p.scheduler.(stealer)
+	if !ok {
+		for item := range own {
+			callback(item)
+			p.pending.Done()
+		}
+		return
+	}
+
+	for {
+		select {
+		case item, ok := <-own:
+			if !ok {
+				return
+			}
+			callback(item)
+			p.pending.Done()
+			continue
+		default:
+		}
+		// Take the wake channel before stealing, so that an item submitted
+		// after a failed steal still wakes the worker.
+		wake := p.idle()
+		if item, ok := s.steal(i, p.queues); ok {
+			callback(item)
+			p.pending.Done()
+			continue
+		}
+		select {
+		case item, ok := <-own:
+			if !ok {
+				return
+			}
+			callback(item)
+			p.pending.Done()
+		case <-wake:
+		}
+	}
+}
+
+// idle returns a channel that is closed when the next item is submitted.
+func (p *scheduledPool) idle() <-chan struct{} {
+	p.wakeLock.Lock()
+	defer p.wakeLock.Unlock()
+	if p.wake == nil {
+		p.wake = make(chan struct{})
+	}
+	return p.wake
+}
+
+// SubmitBlocking dispatches an item. It must not be called concurrently.
+func (p *scheduledPool) SubmitBlocking(item string) error {
+	p.pending.Add(1)
+	p.scheduler.Dispatch(item, p.next%len(p.queues), p.queues)
+	p.next++
+	p.wakeLock.Lock()
+	if p.wake != nil {
+		close(p.wake) // Let idle workers steal the item
+		p.wake = nil
+	}
+	p.wakeLock.Unlock()
+	return nil
+}
+
+func (p *scheduledPool) wait() {
+	p.pending.Wait()
+}
+
+func (p *scheduledPool) Close() error {
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.done.Wait()
+	return nil
+}
+
+type roundRobinScheduler struct{}
+
+// RoundRobinScheduler returns a WorkerScheduler that deals items out to the
+// workers in turn. Since every worker has its own queue, a slow item only
+// holds up the items queued behind it on the same worker.
+func RoundRobinScheduler() WorkerScheduler {
+	return roundRobinScheduler{}
+}
+
+func (roundRobinScheduler) Dispatch(item string, workerID int, workers []chan string) {
+	workers[workerID] <- item
+}
+
+// stealer is implemented by schedulers whose workers take items queued for
+// other workers when their own queue is empty.
+type stealer interface {
+	steal(worker int, queues []chan string) (string, bool)
+}
+
+type workStealingScheduler struct {
+	concurrency int
+}
+
+// WorkStealingScheduler returns a WorkerScheduler that deals items out to the
+// workers in turn, like RoundRobinScheduler, but lets a worker whose queue is
+// empty take items from the longest queue of the others, so that no worker
+// sits idle while another falls behind. Idle workers sleep until the next item
+// is submitted, so they cost nothing between waves. Unlike classic work
+// stealing, which takes from the tail of a queue, items are stolen from the
+// head, the item that has waited longest, since a channel offers no other end.
+// concurrency should be the concurrency of the wave.
+func WorkStealingScheduler(concurrency int) WorkerScheduler {
+	return workStealingScheduler{concurrency: concurrency}
+}
+
+func (s workStealingScheduler) Dispatch(item string, workerID int, workers []chan string) {
+	n := s.concurrency
+	if n < 1 || n > len(workers) {
+		n = len(workers)
+	}
+	workers[workerID%n] <- item
+}
+
+// steal takes the item at the head of the longest queue other than that of
+// worker, without blocking.
+func (workStealingScheduler) steal(worker int, queues []chan string) (string, bool) {
+	victim := -1
+	for i, q := range queues {
+		if i != worker && len(q) > 0 && (victim < 0 || len(q) > len(queues[victim])) {
+			victim = i
+		}
+	}
+	if victim < 0 {
+		return "", false
+	}
+	select {
+	case item, ok := <-queues[victim]:
+		return item, ok
+	default:
+		return "", false
+	}
+}
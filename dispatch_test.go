@@ -0,0 +1,191 @@
+package wave
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingScheduler records which worker inner sends every item to.
+type recordingScheduler struct {
+	inner   WorkerScheduler
+	workers map[string][]int
+	lock    sync.Mutex
+}
+
+func (s *recordingScheduler) Dispatch(item string, workerID int, workers []chan string) {
+	probes := make([]chan string, len(workers))
+	for i := range probes {
+		probes[i] = make(chan string, 1)
+	}
+	s.inner.Dispatch(item, workerID, probes)
+	for i, probe := range probes {
+		if len(probe) == 1 {
+			s.lock.Lock()
+			s.workers[item] = append(s.workers[item], i)
+			s.lock.Unlock()
+			workers[i] <- <-probe
+		}
+	}
+}
+
+func TestConsistentHashScheduler(t *testing.T) {
+	s := &recordingScheduler{inner: ConsistentHashScheduler(4), workers: map[string][]int{}}
+	var w *Handle
+	w = Continuous(4, FakeEndpoints(), func(string) {})
+	w.SetScheduler(s)
+	w.AfterEach(func() {
+		if w.waves.Load() == 3 {
+			go w.Finish()
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	used := map[int]bool{}
+	for _, val := range FakeEndpoints() {
+		workers := s.workers[val]
+		if len(workers) < 3 {
+			t.Fatal("Expected", val, "to be dispatched in every wave, got", workers)
+		}
+		for _, worker := range workers {
+			if worker != workers[0] {
+				t.Error("Expected", val, "to always go to the same worker, got", workers)
+			}
+		}
+		used[workers[0]] = true
+	}
+	if len(used) < 2 {
+		t.Error("Expected items to be spread over several workers, got", used)
+	}
+	if n, waves := w.processed.Load(), w.waves.Load(); n != waves*numPorts {
+		t.Error("Expected", waves*numPorts, "items processed, got", n)
+	}
+}
+
+func TestRoundRobinScheduler(t *testing.T) {
+	vals := make([]string, 15)
+	for i := range vals {
+		vals[i] = strconv.Itoa(i)
+	}
+	release := make(chan struct{})
+	w := Once(5, vals, func(val string) {
+		if val == "2" {
+			<-release
+		}
+	})
+	w.SetScheduler(RoundRobinScheduler())
+	w.Start()
+
+	// Items 2, 7 and 12 go to the third worker; the others don't wait for them.
+	deadline := time.Now().Add(time.Second)
+	for w.CompletedCount() < 12 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected 12 items to complete around the slow one, got", w.CompletedCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := w.CompletedCount(); n != 12 {
+		t.Error("Expected the items behind the slow one to wait, got", n, "completed")
+	}
+	close(release)
+	w.Wait()
+	if n := w.CompletedCount(); n != len(vals) {
+		t.Error("Expected", len(vals), "items, got", n)
+	}
+}
+
+// firstWorker sends every item to the first worker.
+type firstWorker struct{}
+
+func (firstWorker) Dispatch(item string, workerID int, workers []chan string) {
+	workers[0] <- item
+}
+
+// stealingFirstWorker sends every item to the first worker, and lets the
+// others steal from it.
+type stealingFirstWorker struct {
+	firstWorker
+	workStealingScheduler
+}
+
+func (s stealingFirstWorker) Dispatch(item string, workerID int, workers []chan string) {
+	s.firstWorker.Dispatch(item, workerID, workers)
+}
+
+// timeUneven runs a wave of 100 slow items that are all given to one of 5
+// workers.
+func timeUneven(s WorkerScheduler) time.Duration {
+	w := Once(5, make([]string, 100), func(string) { time.Sleep(time.Millisecond) })
+	w.SetScheduler(s)
+	began := time.Now()
+	w.Finish()
+	return time.Since(began)
+}
+
+func TestWorkStealingScheduler(t *testing.T) {
+	unbalanced := timeUneven(firstWorker{})
+	stealing := timeUneven(stealingFirstWorker{})
+	if stealing > unbalanced/2 {
+		t.Error("Expected stealing to at least halve the time of", unbalanced, "got", stealing)
+	}
+
+	vals := make([]string, 50)
+	var lock sync.Mutex
+	count := 0
+	w := Once(5, vals, func(string) {
+		lock.Lock()
+		count++
+		lock.Unlock()
+	})
+	w.SetScheduler(WorkStealingScheduler(5))
+	w.Finish()
+	if count != len(vals) {
+		t.Error("Expected every item to be processed once, got", count)
+	}
+}
+
+// countingStealer counts the steal attempts of a WorkStealingScheduler.
+type countingStealer struct {
+	workStealingScheduler
+	steals atomic.Int64
+}
+
+func (s *countingStealer) steal(worker int, queues []chan string) (string, bool) {
+	s.steals.Add(1)
+	return s.workStealingScheduler.steal(worker, queues)
+}
+
+func TestWorkStealingSchedulerIdle(t *testing.T) {
+	s := &countingStealer{workStealingScheduler: workStealingScheduler{concurrency: 4}}
+	var processed atomic.Int64
+	p := newScheduledPool(4, s, func(string) { processed.Add(1) })
+	time.Sleep(20 * time.Millisecond)
+	if n := s.steals.Load(); n > 4 {
+		t.Error("Expected idle workers to wait for items instead of polling, got", n, "steal attempts")
+	}
+
+	for i := 0; i < 20; i++ {
+		p.SubmitBlocking(strconv.Itoa(i))
+	}
+	p.wait()
+	p.Close()
+	if n := processed.Load(); n != 20 {
+		t.Error("Expected every item to be processed once, got", n)
+	}
+}
+
+func BenchmarkUnevenLoad(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		s    WorkerScheduler
+	}{{"NoStealing", firstWorker{}}, {"Stealing", stealingFirstWorker{}}} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				timeUneven(bench.s)
+			}
+		})
+	}
+}
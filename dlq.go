@@ -0,0 +1,63 @@
+package wave
+
+import (
+	"errors"
+	"time"
+)
+
+// DeadLetter is an item that failed, as sent to the channel given to SetDLQ.
+type DeadLetter struct {
+	Val      string
+	Err      error
+	Attempts int // Calls made by RetryMiddleware, or 1 without it
+	Time     time.Time
+}
+
+// SetDLQ makes the wave send every item whose callback chain fails to dlq, so
+// that it can be inspected or retried later. Items are sent without blocking;
+// if dlq is full the item is dropped and counted by DLQDropped. Use
+// RetryMiddleware to retry items before they are given up on.
+func (h *Handle) SetDLQ(dlq chan<- DeadLetter) {
+	h.stateLock.Lock()
+	h.dlq = dlq
+	h.stateLock.Unlock()
+}
+
+// DLQDropped returns how many failed items were dropped because the channel
+// given to SetDLQ was full.
+func (h *Handle) DLQDropped() int64 {
+	return h.dlqDropped.Load()
+}
+
+func (h *Handle) deadLetter(val string, err error) {
+	h.stateLock.RLock()
+	dlq := h.dlq
+	h.stateLock.RUnlock()
+	if dlq == nil {
+		return
+	}
+	letter := DeadLetter{Val: val, Err: err, Attempts: 1, Time: time.Now()}
+	var retried *retryError
+	if errors.As(err, &retried) {
+		letter.Attempts = retried.attempts
+	}
+	select {
+	case dlq <- letter:
+	default:
+		h.dlqDropped.Add(1)
+	}
+}
+
+// retryError is the last error of an item that RetryMiddleware gave up on.
+type retryError struct {
+	err      error
+	attempts int
+}
+
+func (e *retryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,51 @@
+package wave
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDLQ(t *testing.T) {
+	dlq := make(chan DeadLetter, numPorts)
+	w := once(2, SliceSource(FakeEndpoints()), func(val string) error {
+		if val == ":3003" {
+			return errFault
+		}
+		return nil
+	})
+	w.Use(RetryMiddleware(3, 0))
+	w.SetDLQ(dlq)
+	w.Finish()
+
+	if len(dlq) != 1 {
+		t.Fatal("Expected 1 dead letter, got", len(dlq))
+	}
+	letter := <-dlq
+	if letter.Val != ":3003" || !errors.Is(letter.Err, errFault) || letter.Attempts != 3 || letter.Time.IsZero() {
+		t.Error("Unexpected dead letter", letter)
+	}
+	if n := w.DLQDropped(); n != 0 {
+		t.Error("Expected no dropped items, got", n)
+	}
+}
+
+func TestDLQOverflow(t *testing.T) {
+	dlq := make(chan DeadLetter, 1)
+	w := once(numPorts, SliceSource(FakeEndpoints()), func(val string) error {
+		time.Sleep(time.Millisecond)
+		return errFault
+	})
+	w.SetDLQ(dlq)
+	w.Finish()
+
+	if len(dlq) != 1 {
+		t.Error("Expected a full DLQ, got", len(dlq))
+	}
+	if letter := <-dlq; letter.Attempts != 1 {
+		t.Error("Expected 1 attempt without retries, got", letter.Attempts)
+	}
+	if n := w.DLQDropped(); n != numPorts-1 {
+		t.Error("Expected", numPorts-1, "dropped items, got", n)
+	}
+}
@@ -0,0 +1,150 @@
+package wave
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ItemError is an error returned by the callback chain for a single item.
+type ItemError struct {
+	Val string
+	Err error
+}
+
+func (e ItemError) Error() string {
+	return e.Val + ": " + e.Err.Error()
+}
+
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError holds the errors of the items in a wave.
+type MultiError []ItemError
+
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, e := range m {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("wave: %d items failed: %s", len(m), strings.Join(msgs, "; "))
+}
+
+// Errors returns the errors of the items in the current or most recent wave,
+// in the order they occurred. It returns nil if there were none.
+func (h *Handle) Errors() MultiError {
+	h.errorsLock.Lock()
+	defer h.errorsLock.Unlock()
+	if len(h.errors) == 0 {
+		return nil
+	}
+	return append(MultiError(nil), h.errors...)
+}
+
+func (h *Handle) recordError(val string, err error) {
+	h.errorCount.Add(1)
+	h.errorsLock.Lock()
+	h.errors = append(h.errors, ItemError{Val: val, Err: err})
+	h.errorsCond.Broadcast()
+	h.errorsLock.Unlock()
+}
+
+func (h *Handle) resetErrors() {
+	h.errorsLock.Lock()
+	h.errors = nil
+	h.errorsWave++
+	h.errorsLock.Unlock()
+}
+
+// ForEachError calls fn with the errors of the current or most recent wave in
+// the order they occurred, until fn returns false. If the wave has not
+// stopped, ForEachError then waits for more errors and passes them to fn as
+// they occur, including those of later waves of a Continuous wave, and returns
+// once the wave stops. It is safe to call concurrently with the wave.
+func (h *Handle) ForEachError(fn func(ItemError) bool) {
+	h.errorsLock.Lock()
+	defer h.errorsLock.Unlock()
+	wave, i := h.errorsWave, 0
+	for {
+		if wave != h.errorsWave {
+			wave, i = h.errorsWave, 0
+		}
+		if i < len(h.errors) {
+			e := h.errors[i]
+			i++
+			h.errorsLock.Unlock()
+			ok := fn(e)
+			h.errorsLock.Lock()
+			if !ok {
+				return
+			}
+			continue
+		}
+		if h.stopped() {
+			return
+		}
+		h.errorsCond.Wait()
+	}
+}
+
+// GroupErrors returns the errors of the current or most recent wave grouped
+// by keyFn applied to their items, for example by datacenter prefix to spot a
+// localized outage.
+func (h *Handle) GroupErrors(keyFn func(val string) string) map[string][]ItemError {
+	groups := map[string][]ItemError{}
+	for _, e := range h.Errors() {
+		key := keyFn(e.Val)
+		groups[key] = append(groups[key], e)
+	}
+	return groups
+}
+
+// ReplayErrors returns a new Once wave over the items that failed in the
+// current or most recent wave, with the same concurrency, callback and
+// middleware, so that only the failed items are retried. Items are replayed
+// as they were before SetTransform, which is copied along with the dead letter
+// queue and panic recovery. The error of a failed source is not replayed. The
+// returned wave is not started.
+//
+// Interleaved waves cannot route items back to their origin once the wave has
+// run, so ReplayErrors returns ErrNotRecoverable for them.
+func (h *Handle) ReplayErrors() (*Handle, error) {
+	if h.interleaved != nil {
+		return nil, ErrNotRecoverable
+	}
+	var vals []string
+	for _, e := range h.Errors() {
+		var srcErr *sourceError
+		if errors.As(e.Err, &srcErr) {
+			continue
+		}
+		vals = append(vals, e.Val)
+	}
+	r := once(h.concurrency, nil, h.callback)
+	r.setVals(vals)
+	h.funcsLock.RLock()
+	middleware := append([]CallbackMiddleware(nil), h.middleware...)
+	h.funcsLock.RUnlock()
+	r.Use(middleware...)
+
+	h.stateLock.RLock()
+	r.transform, r.dlq = h.transform, h.dlq
+	h.stateLock.RUnlock()
+	if h.recovery != nil {
+		r.recovery = &panicRecovery{maxRestarts: h.recovery.maxRestarts, onPanic: h.recovery.onPanic}
+	}
+	return r, nil
+}
+
+// ReplayErrorsCtx is like ReplayErrors, but the returned wave is interrupted
+// when ctx is done.
+func (h *Handle) ReplayErrorsCtx(ctx context.Context) (*Handle, error) {
+	r, err := h.ReplayErrors()
+	if err != nil {
+		return nil, err
+	}
+	r.setWatch(ctx, 0)
+	return r, nil
+}
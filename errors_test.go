@@ -0,0 +1,176 @@
+package wave
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachErrorStreaming(t *testing.T) {
+	w := once(3, SliceSource(FakeEndpoints()), func(string) error { return errFault })
+	done := make(chan []ItemError)
+	go func() {
+		var seen []ItemError
+		w.ForEachError(func(e ItemError) bool {
+			seen = append(seen, e)
+			return true
+		})
+		done <- seen
+	}()
+	w.Start()
+
+	seen := <-done
+	if len(seen) != numPorts {
+		t.Fatal("Expected", numPorts, "errors, got", len(seen))
+	}
+	for _, e := range seen {
+		if !errors.Is(e, errFault) {
+			t.Error("Unexpected error", e)
+		}
+	}
+	if !w.stopped() {
+		t.Error("Expected ForEachError to return once the wave stopped")
+	}
+}
+
+func TestForEachErrorStop(t *testing.T) {
+	w := once(1, SliceSource(FakeEndpoints()), func(string) error { return errFault })
+	w.Finish()
+
+	calls := 0
+	w.ForEachError(func(ItemError) bool {
+		calls++
+		return calls < 3
+	})
+	if calls != 3 {
+		t.Error("Expected iteration to stop after 3 errors, got", calls)
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	hosts := []string{"dc1-r1-a", "dc1-r2-b", "dc2-r1-c", "dc2-r1-d", "dc3-r1-e"}
+	w := once(2, SliceSource(hosts), func(val string) error {
+		if strings.HasPrefix(val, "dc3") {
+			return nil
+		}
+		return errFault
+	})
+	w.Finish()
+
+	groups := w.GroupErrors(func(val string) string {
+		return strings.SplitN(val, "-", 2)[0]
+	})
+	if len(groups) != 2 || len(groups["dc1"]) != 2 || len(groups["dc2"]) != 2 {
+		t.Error("Expected 2 errors each for dc1 and dc2, got", groups)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	var lock sync.Mutex
+	calls := map[string]int{}
+	w := once(4, SliceSource(FakeEndpoints()), func(val string) error {
+		lock.Lock()
+		calls[val]++
+		lock.Unlock()
+		if val == ":3001" || val == ":3004" || val == ":3008" {
+			return errFault
+		}
+		return nil
+	})
+	w.Finish()
+
+	r, err := w.ReplayErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := r.Snapshot().State; s != StateIdle {
+		t.Error("Expected the replay not to be started, got", s)
+	}
+	r.Finish()
+	if n := r.CompletedCount(); n != 3 {
+		t.Error("Expected 3 items replayed, got", n)
+	}
+	for val, n := range calls {
+		want := 1
+		if val == ":3001" || val == ":3004" || val == ":3008" {
+			want = 2
+		}
+		if n != want {
+			t.Error("Expected", val, "to be called", want, "times, got", n)
+		}
+	}
+}
+
+func TestReplayErrorsCtx(t *testing.T) {
+	w := once(1, SliceSource(FakeEndpoints()), func(string) error { return errFault })
+	w.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r, err := w.ReplayErrorsCtx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Start()
+	r.Wait()
+	if s := r.Snapshot().State; s != StateInterrupted {
+		t.Error("Expected a cancelled replay to be interrupted, got", s)
+	}
+}
+
+func TestReplayErrorsTransform(t *testing.T) {
+	var got []string
+	var lock sync.Mutex
+	w := once(1, SliceSource([]string{":3000"}), func(val string) error {
+		lock.Lock()
+		got = append(got, val)
+		lock.Unlock()
+		return errFault
+	})
+	w.SetTransform(func(val string) string { return "http://localhost" + val })
+	w.Finish()
+
+	r, err := w.ReplayErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Finish()
+	if len(got) != 2 || got[1] != "http://localhost:3000" {
+		t.Error("Expected the replayed item to be transformed, got", got)
+	}
+}
+
+func TestReplayErrorsInterleaved(t *testing.T) {
+	h := once(1, SliceSource([]string{"h1"}), func(string) error { return errFault })
+	other := once(1, SliceSource([]string{"l1"}), func(string) error { return errFault })
+	w := h.Interleave(other)
+	w.Finish()
+
+	if _, err := w.ReplayErrors(); !errors.Is(err, ErrNotRecoverable) {
+		t.Error("Expected ErrNotRecoverable for an interleaved wave, got", err)
+	}
+}
+
+func TestReplayErrorsSkipsSourceError(t *testing.T) {
+	var calls atomic.Int32
+	w := once(1, &failedSource{err: errFault}, func(string) error {
+		calls.Add(1)
+		return nil
+	})
+	w.Finish()
+	if n := len(w.Errors()); n != 1 {
+		t.Fatal("Expected the source error to be recorded, got", n)
+	}
+
+	r, err := w.ReplayErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Finish()
+	if n := calls.Load(); n != 0 {
+		t.Error("Expected the source error not to be replayed, got", n, "calls")
+	}
+}
@@ -0,0 +1,83 @@
+package wave
+
+import (
+	"strconv"
+	"time"
+)
+
+// EventKind identifies what an Event reports.
+type EventKind int
+
+// Kinds of Event.
+const (
+	EventItemStarted EventKind = iota
+	EventItemFinished
+	EventItemErrored
+	EventWaveStarted
+	EventWaveFinished
+	EventWaveInterrupted
+)
+
+var eventKindNames = []string{
+	"ItemStarted",
+	"ItemFinished",
+	"ItemErrored",
+	"WaveStarted",
+	"WaveFinished",
+	"WaveInterrupted",
+}
+
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventKindNames) {
+		return "EventKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return eventKindNames[k]
+}
+
+// Event reports a change in the lifecycle of a wave or one of its items.
+type Event struct {
+	Kind       EventKind
+	WaveName   string // Name of the wave, empty if it has none
+	Item       string // Empty for wave events
+	WaveNumber int    // Starting at 1, incremented for every repetition
+	Timestamp  time.Time
+	Err        error // Set for EventItemErrored
+}
+
+// Events returns a channel that receives an Event for every item and wave
+// from now on. Each call returns a new channel with room for bufsize events.
+// Events are dropped rather than holding up the wave if the channel is full.
+// The channel is closed when the wave stops.
+func (h *Handle) Events(bufsize int) <-chan Event {
+	ch := make(chan Event, bufsize)
+	h.eventsLock.Lock()
+	if h.stopped() {
+		close(ch)
+	} else {
+		h.eventChans = append(h.eventChans, ch)
+	}
+	h.eventsLock.Unlock()
+	return ch
+}
+
+func (h *Handle) emit(kind EventKind, item string, err error) {
+	h.eventsLock.Lock()
+	defer h.eventsLock.Unlock()
+	if len(h.eventChans) == 0 {
+		return
+	}
+	e := Event{
+		Kind:       kind,
+		WaveName:   h.waveName(),
+		Item:       item,
+		WaveNumber: int(h.waves.Load()),
+		Timestamp:  time.Now(),
+		Err:        err,
+	}
+	for _, ch := range h.eventChans {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package wave
+
+import "testing"
+
+func TestEvents(t *testing.T) {
+	w := once(3, SliceSource(FakeEndpoints()), func(host string) error {
+		if host == ":3009" {
+			return errFault
+		}
+		return nil
+	})
+	a, b := w.Events(100), w.Events(100)
+	w.Finish()
+
+	for _, ch := range []<-chan Event{a, b} {
+		var events []Event
+		counts := map[EventKind]int{}
+		for e := range ch { // Closed when the wave stops
+			events = append(events, e)
+			counts[e.Kind]++
+			if e.WaveNumber != 1 {
+				t.Error("Expected wave number 1, got", e.WaveNumber)
+			}
+		}
+		if len(events) != 2*numPorts+2 {
+			t.Fatal("Expected", 2*numPorts+2, "events, got", len(events))
+		}
+		if first, last := events[0].Kind, events[len(events)-1].Kind; first != EventWaveStarted || last != EventWaveFinished {
+			t.Error("Expected WaveStarted first and WaveFinished last, got", first, last)
+		}
+		if counts[EventItemStarted] != numPorts || counts[EventItemFinished] != numPorts-1 || counts[EventItemErrored] != 1 {
+			t.Error("Unexpected event counts", counts)
+		}
+	}
+
+	if _, ok := <-w.Events(1); ok {
+		t.Error("Expected a closed channel after the wave stopped")
+	}
+}
+
+func TestEventsDropWhenFull(t *testing.T) {
+	w := Once(3, FakeEndpoints(), func(string) {})
+	ch := w.Events(1)
+	w.Finish() // Must not block on the full channel
+
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != 1 {
+		t.Error("Expected 1 buffered event, got", n)
+	}
+}
@@ -0,0 +1,42 @@
+package wave
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Seed for fault injectors, so that a test run always injects the same faults.
+const faultSeed = 0x7761766573
+
+// NewFaultInjector returns a wrapper that injects artificial faults into a
+// callback, which is useful for testing how a wave behaves against slow or
+// failing hosts.
+// With probability latencyPct the wrapped callback first sleeps for a random
+// duration of up to latencyMax. With probability errorPct it then returns
+// errorFn(val) instead of calling the callback.
+// Faults are drawn from a deterministic source, so every injector produces
+// the same sequence of decisions.
+func NewFaultInjector(latencyPct float64, latencyMax time.Duration, errorPct float64, errorFn func(string) error) func(func(string) error) func(string) error {
+	rng := rand.New(rand.NewPCG(faultSeed, faultSeed))
+	lock := sync.Mutex{} // Guards rng
+	return func(next func(string) error) func(string) error {
+		return func(val string) error {
+			lock.Lock()
+			var delay time.Duration
+			if rng.Float64() < latencyPct && latencyMax > 0 {
+				delay = time.Duration(rng.Int64N(int64(latencyMax)))
+			}
+			fail := rng.Float64() < errorPct
+			lock.Unlock()
+
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+			if fail {
+				return errorFn(val)
+			}
+			return next(val)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package wave
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errFault = errors.New("injected fault")
+
+func TestFaultInjectorErrors(t *testing.T) {
+	calls := 0
+	inject := NewFaultInjector(0, 0, 1, func(string) error { return errFault })
+	f := inject(func(string) error {
+		calls++
+		return nil
+	})
+
+	for _, host := range FakeEndpoints() {
+		if err := f(host); err != errFault {
+			t.Error("Expected injected fault, got", err)
+		}
+	}
+	if calls != 0 {
+		t.Error("Expected 0 calls, got", calls)
+	}
+}
+
+func TestFaultInjectorDeterministic(t *testing.T) {
+	run := func() []bool {
+		var failed []bool
+		inject := NewFaultInjector(0.5, time.Millisecond, 0.5, func(string) error { return errFault })
+		f := inject(func(string) error { return nil })
+		for _, host := range FakeEndpoints() {
+			failed = append(failed, f(host) != nil)
+		}
+		return failed
+	}
+
+	a, b := run(), run()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("Expected identical fault sequences, got", a, b)
+		}
+	}
+}
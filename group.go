@@ -0,0 +1,134 @@
+package wave
+
+import "sync"
+
+// WaitAny returns a Handle that stops as soon as any of handles stops.
+// Interrupt and Finish on the returned Handle are passed on to all of
+// handles. The handles are not started. With no handles, the returned Handle
+// is already stopped.
+func WaitAny(handles ...*Handle) *Handle {
+	return waitFor(handles, 1)
+}
+
+// WaitAll returns a Handle that stops once all of handles have stopped.
+// Interrupt and Finish on the returned Handle are passed on to all of
+// handles. The handles are not started.
+func WaitAll(handles ...*Handle) *Handle {
+	return waitFor(handles, len(handles))
+}
+
+// waitFor returns a Handle that stops once n of handles have stopped.
+func waitFor(handles []*Handle, n int) *Handle {
+	h := newHandle(0, func() Source { return SliceSource(nil) }, nil)
+	stopped := make(chan struct{}, len(handles))
+	for _, input := range handles {
+		go func() {
+			<-input.stopChan
+			stopped <- struct{}{}
+		}()
+	}
+	go func() {
+		for i := 0; i < n && len(handles) > 0; i++ {
+			<-stopped
+		}
+		h.stop()
+	}()
+	go func() {
+		var forward func(*Handle)
+		select {
+		case <-h.interruptChan:
+			forward = (*Handle).Interrupt
+		case <-h.finishChan:
+			forward = (*Handle).Finish
+		case <-h.stopChan:
+			return
+		}
+		for _, input := range handles {
+			go forward(input)
+		}
+	}()
+	return h
+}
+
+// HandleGroup manages the lifecycle of several Handles together. The zero
+// value is an empty group ready to use.
+type HandleGroup struct {
+	handles   []*Handle
+	running   int // Handles that have not stopped yet
+	started   bool
+	stopFuncs []func()
+	lock      sync.Mutex // Guards all fields
+}
+
+// Add puts h in the group. If StartAll has been called, h is started too.
+func (g *HandleGroup) Add(h *Handle) {
+	g.lock.Lock()
+	g.handles = append(g.handles, h)
+	g.running++
+	started := g.started
+	g.lock.Unlock()
+
+	if started {
+		h.Start()
+	}
+	go func() {
+		<-h.stopChan
+		g.lock.Lock()
+		g.running--
+		var fs []func()
+		if g.running == 0 {
+			fs = g.stopFuncs
+		}
+		g.lock.Unlock()
+		for _, f := range fs {
+			f()
+		}
+	}()
+}
+
+func (g *HandleGroup) each(f func(*Handle)) {
+	g.lock.Lock()
+	handles := append([]*Handle(nil), g.handles...)
+	g.lock.Unlock()
+
+	wg := sync.WaitGroup{}
+	for _, h := range handles {
+		wg.Add(1)
+		go func() { f(h); wg.Done() }()
+	}
+	wg.Wait()
+}
+
+// StartAll starts every handle in the group, and any handle added later.
+func (g *HandleGroup) StartAll() {
+	g.lock.Lock()
+	g.started = true
+	g.lock.Unlock()
+	g.each((*Handle).Start)
+}
+
+// InterruptAll interrupts every handle in the group and blocks until they
+// have stopped.
+func (g *HandleGroup) InterruptAll() {
+	g.each((*Handle).Interrupt)
+}
+
+// FinishAll finishes every handle in the group and blocks until they have
+// stopped.
+func (g *HandleGroup) FinishAll() {
+	g.each((*Handle).Finish)
+}
+
+// WaitAll blocks until every handle in the group has stopped. The group can
+// be reused afterwards by adding more handles.
+func (g *HandleGroup) WaitAll() {
+	g.each((*Handle).Wait)
+}
+
+// OnStop registers a function to be called whenever all handles in the group
+// have stopped.
+func (g *HandleGroup) OnStop(f func()) {
+	g.lock.Lock()
+	g.stopFuncs = append(g.stopFuncs, f)
+	g.lock.Unlock()
+}
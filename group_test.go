@@ -0,0 +1,83 @@
+package wave
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sleeper returns a handle whose single item sleeps for d.
+func sleeper(d time.Duration) *Handle {
+	return Once(1, []string{"host"}, func(string) {
+		time.Sleep(d)
+	})
+}
+
+func TestWaitAny(t *testing.T) {
+	handles := []*Handle{sleeper(300 * time.Millisecond), sleeper(10 * time.Millisecond), sleeper(300 * time.Millisecond)}
+	first := WaitAny(handles...)
+	began := time.Now()
+	for _, h := range handles {
+		h.Start()
+	}
+	first.Wait()
+
+	if d := time.Since(began); d > 200*time.Millisecond {
+		t.Error("Expected WaitAny to return after the fastest handle, took", d)
+	}
+	first.Interrupt()
+	for _, h := range handles {
+		h.Wait()
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	handles := []*Handle{sleeper(time.Millisecond), sleeper(20 * time.Millisecond), sleeper(10 * time.Millisecond)}
+	all := WaitAll(handles...)
+	all.Finish() // Finishing the group starts and finishes every handle
+	for i, h := range handles {
+		if !h.stopped() {
+			t.Error("Expected handle", i, "to be stopped")
+		}
+	}
+
+	WaitAll().Wait() // No handles, so already stopped
+}
+
+func TestHandleGroup(t *testing.T) {
+	var stops atomic.Int32
+	g := HandleGroup{}
+	g.OnStop(func() {
+		stops.Add(1)
+	})
+	for i := 0; i < 10; i++ {
+		g.Add(sleeper(10 * time.Millisecond))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.FinishAll()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("FinishAll did not return in time")
+	}
+
+	g.StartAll()
+	late := sleeper(0)
+	g.Add(late) // Started automatically
+	g.WaitAll()
+	if !late.stopped() {
+		t.Error("Expected handle added after StartAll to run")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for stops.Load() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := stops.Load(); n != 2 {
+		t.Error("Expected OnStop to fire twice, got", n)
+	}
+}
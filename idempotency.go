@@ -0,0 +1,75 @@
+package wave
+
+import "sync"
+
+// IdempotencyStore records which items have been claimed for processing, so
+// that an item is not processed twice, even by another Handle sharing the
+// store after a restart.
+type IdempotencyStore interface {
+	// Claim reports whether key was unclaimed, and claims it if so.
+	Claim(key string) bool
+	// Release gives up the claim on key, so that it can be claimed again.
+	Release(key string)
+}
+
+// SetIdempotencyStore makes workers claim every item in store before passing
+// it to the callback chain, and skip items that are already claimed. The
+// claim is released if the item fails, so that a later attempt can retry it,
+// and kept if it succeeds. Claims are scoped to a wave: the claims of a wave
+// are released when the next wave of h starts, so every wave of a Continuous
+// wave processes the items again. The claims of the last wave are kept, so a
+// Handle restarted after a crash skips the items that were already processed.
+func (h *Handle) SetIdempotencyStore(store IdempotencyStore) {
+	h.stateLock.Lock()
+	h.idempotency = store
+	h.stateLock.Unlock()
+}
+
+// waveClaims are the claims kept by the items that succeeded in the current
+// wave.
+type waveClaims struct {
+	claims []claim
+	lock   sync.Mutex // Guards claims
+}
+
+type claim struct {
+	store IdempotencyStore
+	key   string
+}
+
+func (c *waveClaims) keep(store IdempotencyStore, key string) {
+	c.lock.Lock()
+	c.claims = append(c.claims, claim{store: store, key: key})
+	c.lock.Unlock()
+}
+
+// release releases the claims of the previous wave. It is called when a wave
+// starts.
+func (c *waveClaims) release() {
+	c.lock.Lock()
+	claims := c.claims
+	c.claims = nil
+	c.lock.Unlock()
+	for _, cl := range claims {
+		cl.store.Release(cl.key)
+	}
+}
+
+type inMemoryIdempotencyStore struct {
+	claims sync.Map
+}
+
+// NewInMemoryIdempotencyStore returns an IdempotencyStore that keeps claims in
+// memory. It can be shared by several Handles.
+func NewInMemoryIdempotencyStore() IdempotencyStore {
+	return &inMemoryIdempotencyStore{}
+}
+
+func (s *inMemoryIdempotencyStore) Claim(key string) bool {
+	_, claimed := s.claims.LoadOrStore(key, struct{}{})
+	return !claimed
+}
+
+func (s *inMemoryIdempotencyStore) Release(key string) {
+	s.claims.Delete(key)
+}
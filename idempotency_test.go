@@ -0,0 +1,85 @@
+package wave
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	store := NewInMemoryIdempotencyStore()
+	var lock sync.Mutex
+	counts := map[string]int{}
+	count := func(val string) {
+		lock.Lock()
+		counts[val]++
+		lock.Unlock()
+	}
+
+	var w *Handle
+	n := 0
+	w = once(1, SliceSource(FakeEndpoints()), func(val string) error {
+		count(val)
+		if n++; n == 5 {
+			go w.Interrupt() // Crash mid-wave
+			<-w.interruptChan
+		}
+		if val == ":3001" {
+			return errFault
+		}
+		return nil
+	})
+	w.SetIdempotencyStore(store)
+	w.Start()
+	w.Wait()
+
+	restarted := once(3, SliceSource(FakeEndpoints()), func(val string) error {
+		count(val)
+		return nil
+	})
+	restarted.SetIdempotencyStore(store)
+	restarted.Finish()
+
+	for _, val := range FakeEndpoints() {
+		want := 1
+		if val == ":3001" {
+			want = 2 // Failed, so released for a retry
+		}
+		if counts[val] != want {
+			t.Error("Expected", val, "to be processed", want, "times, got", counts[val])
+		}
+	}
+}
+
+func TestInMemoryIdempotencyStore(t *testing.T) {
+	store := NewInMemoryIdempotencyStore()
+	if !store.Claim("a") || store.Claim("a") {
+		t.Error("Expected only the first claim to succeed")
+	}
+	store.Release("a")
+	if !store.Claim("a") {
+		t.Error("Expected a released key to be claimable again")
+	}
+}
+
+func TestIdempotencyStoreContinuous(t *testing.T) {
+	store := NewInMemoryIdempotencyStore()
+	var lock sync.Mutex
+	counts := map[string]int{}
+	w := ContinuousWithOptions(FakeEndpoints(), func(val string) {
+		lock.Lock()
+		counts[val]++
+		lock.Unlock()
+	}, WithConcurrency(2), WithMaxIterations(3))
+	w.SetIdempotencyStore(store)
+	w.Start()
+	w.Wait()
+
+	for _, val := range FakeEndpoints() {
+		if counts[val] != 3 {
+			t.Error("Expected", val, "to be processed by every wave, got", counts[val])
+		}
+	}
+	if store.Claim(":3000") {
+		t.Error("Expected the claims of the last wave to be kept")
+	}
+}
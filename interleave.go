@@ -0,0 +1,126 @@
+package wave
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Interleave prepares a wave that processes the items of h and other in
+// alternation, sharing the concurrency of h. Each item is passed to the
+// callback, including any middleware, of the handle it came from. This is
+// useful for mixing a high-priority and a low-priority list of hosts without
+// running two worker pools. h and other should not be started themselves.
+func (h *Handle) Interleave(other *Handle) *Handle {
+	src := &interleavedSource{
+		handles: [2]*Handle{h, other},
+		sources: [2]Source{h.newSource(), other.newSource()},
+		pending: map[string][]*Handle{},
+	}
+	w := once(h.concurrency, src, func(val string) error {
+		return fmt.Errorf("wave: %q did not come from an interleaved wave", val)
+	})
+	w.interleaved = src
+	w.recoverable = false // The sources of h and other have been consumed
+	return w
+}
+
+type interleavedSource struct {
+	handles [2]*Handle
+	sources [2]Source
+	turn    int
+	pending map[string][]*Handle // Handles that items came from, in order
+	lock    sync.Mutex           // Guards pending
+}
+
+func (s *interleavedSource) Next() (string, bool) {
+	for tries := 0; tries < 2; tries++ {
+		i := s.turn
+		s.turn = 1 - s.turn
+		if s.sources[i] == nil {
+			continue
+		}
+		val, ok := s.sources[i].Next()
+		if !ok {
+			s.sources[i] = nil
+			continue
+		}
+		s.lock.Lock()
+		s.pending[val] = append(s.pending[val], s.handles[i])
+		s.lock.Unlock()
+		return val, true
+	}
+	return "", false
+}
+
+// take returns the callback chain of the handle that val came from. Equal
+// items from both handles are interchangeable, so any pending origin will do.
+// Items are keyed by their value before SetTransform, so take must be passed
+// the item as it came from the source.
+func (s *interleavedSource) take(val string) func(string) error {
+	s.lock.Lock()
+	origins, ok := s.pending[val]
+	if !ok {
+		s.lock.Unlock()
+		return func(string) error {
+			return fmt.Errorf("wave: no interleaved wave is waiting for %q", val)
+		}
+	}
+	origin := origins[0]
+	if len(origins) == 1 {
+		delete(s.pending, val)
+	} else {
+		s.pending[val] = origins[1:]
+	}
+	s.lock.Unlock()
+	return origin.chainFor(val)
+}
+
+// skip forgets the origin of val, which a wrapping source has just taken from
+// s and dropped, for example because of SetFilter.
+func (s *interleavedSource) skip(val string) {
+	s.lock.Lock()
+	origins, ok := s.pending[val]
+	if !ok {
+		s.lock.Unlock()
+		return
+	}
+	origin := origins[len(origins)-1] // The item that was just taken
+	if len(origins) == 1 {
+		delete(s.pending, val)
+	} else {
+		s.pending[val] = origins[:len(origins)-1]
+	}
+	s.lock.Unlock()
+	if origin.interleaved != nil {
+		origin.interleaved.skip(val)
+	}
+}
+
+// chainFor returns the callback chain for val. For a wave created by
+// Interleave, it is the chain of the handle val came from, wrapped in the
+// middleware of h, and chainFor must be called exactly once for every item.
+func (h *Handle) chainFor(val string) func(string) error {
+	h.funcsLock.RLock()
+	defer h.funcsLock.RUnlock()
+	if h.interleaved == nil {
+		return h.chain
+	}
+	chain := h.interleaved.take(val)
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		chain = h.middleware[i](chain)
+	}
+	return chain
+}
+
+// skipper is implemented by sources that need to know when a wrapping source
+// drops an item they produced.
+type skipper interface {
+	skip(val string)
+}
+
+// skipped tells src, if it is a skipper, that val was dropped.
+func skipped(src Source, val string) {
+	if s, ok := src.(skipper); ok {
+		s.skip(val)
+	}
+}
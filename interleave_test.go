@@ -0,0 +1,81 @@
+package wave
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInterleave(t *testing.T) {
+	var order, high, low []string
+	both := record(&order)
+	h := Once(1, []string{"h1", "h2", "h3", "shared"}, func(val string) {
+		both(val)
+		high = append(high, val)
+	})
+	other := Once(1, []string{"l1", "shared"}, func(val string) {
+		both(val)
+		low = append(low, val)
+	})
+
+	h.Interleave(other).Finish()
+
+	if want := []string{"h1", "l1", "h2", "shared", "h3", "shared"}; !slices.Equal(order, want) {
+		t.Error("Expected", want, "got", order)
+	}
+	if want := []string{"h1", "h2", "h3", "shared"}; !slices.Equal(high, want) {
+		t.Error("Expected", want, "got", high)
+	}
+	if want := []string{"l1", "shared"}; !slices.Equal(low, want) {
+		t.Error("Expected", want, "got", low)
+	}
+}
+
+func TestInterleaveTransform(t *testing.T) {
+	var high, low []string
+	h := Once(1, []string{"h1", "h2"}, func(val string) { high = append(high, val) })
+	other := Once(1, []string{"l1"}, func(val string) { low = append(low, val) })
+
+	w := h.Interleave(other)
+	w.SetTransform(strings.ToUpper)
+	w.Finish()
+
+	if errs := w.Errors(); errs != nil {
+		t.Error("Unexpected errors", errs)
+	}
+	if want := []string{"H1", "H2"}; !slices.Equal(high, want) {
+		t.Error("Expected", want, "got", high)
+	}
+	if want := []string{"L1"}; !slices.Equal(low, want) {
+		t.Error("Expected", want, "got", low)
+	}
+}
+
+func TestInterleaveDroppedItems(t *testing.T) {
+	var high, low []string
+	h := Once(1, []string{"h1", "shared", "skip"}, func(val string) { high = append(high, val) })
+	other := Once(1, []string{"shared", "l1", "skip"}, func(val string) { low = append(low, val) })
+
+	w := h.Interleave(other)
+	w.SetDeduplication(true)
+	w.SetFilter(func(val string) bool { return val != "skip" })
+	w.Finish()
+
+	// The order is h1, shared, shared, l1, so dedup drops the shared of h.
+	if want := []string{"h1"}; !slices.Equal(high, want) {
+		t.Error("Expected", want, "got", high)
+	}
+	if want := []string{"shared", "l1"}; !slices.Equal(low, want) {
+		t.Error("Expected", want, "got", low)
+	}
+	if n := len(w.interleaved.pending); n != 0 {
+		t.Error("Expected no pending origins, got", w.interleaved.pending)
+	}
+
+	dry := Once(1, []string{"a"}, func(string) {}).Interleave(Once(1, []string{"b"}, func(string) {}))
+	dry.SetDryRun(true)
+	dry.Finish()
+	if n := len(dry.interleaved.pending); n != 0 {
+		t.Error("Expected no pending origins after a dry run, got", dry.interleaved.pending)
+	}
+}
@@ -0,0 +1,299 @@
+package wave
+
+import (
+	"math/rand"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// SetShuffle makes every wave process its items in a random order, so that
+// the same hosts are not always hit first. Continuous waves use a new order
+// for every wave. Only waves over a slice of items can be shuffled.
+func (h *Handle) SetShuffle(shuffle bool) {
+	h.stateLock.Lock()
+	h.shuffle = shuffle
+	h.stateLock.Unlock()
+}
+
+// SetShuffleSeed enables shuffling with a fixed seed, so that every wave uses
+// the same order. Useful for tests.
+func (h *Handle) SetShuffleSeed(seed int64) {
+	h.stateLock.Lock()
+	h.shuffle = true
+	h.shuffleSeed = &seed
+	h.stateLock.Unlock()
+}
+
+// SetDeduplication makes every wave skip items it has already seen, so that
+// each distinct item is processed once per wave.
+func (h *Handle) SetDeduplication(dedup bool) {
+	h.stateLock.Lock()
+	h.dedup = dedup
+	h.stateLock.Unlock()
+}
+
+// DuplicatesRemoved returns how many items the current or most recent wave
+// skipped because of SetDeduplication.
+func (h *Handle) DuplicatesRemoved() int {
+	return int(h.duplicates.Load())
+}
+
+// SetFilter makes waves skip items for which f returns false. Items are
+// filtered as they are taken from the source. The filter is read at the start
+// of every wave, so a new filter takes effect from the next wave.
+func (h *Handle) SetFilter(f func(string) bool) {
+	h.stateLock.Lock()
+	h.filter = f
+	h.stateLock.Unlock()
+}
+
+// ClearFilter removes the filter set by SetFilter.
+func (h *Handle) ClearFilter() {
+	h.SetFilter(nil)
+}
+
+// SkippedCount returns how many items the current or most recent wave skipped
+// because of SetFilter.
+func (h *Handle) SkippedCount() int {
+	return int(h.skipped.Load())
+}
+
+// SetTransform makes workers pass each item through f before handing it to
+// the callback, for example to turn a hostname into a URL. Taps still see the
+// original item. If f panics, the panic is recovered and reported as the
+// error of the item.
+func (h *Handle) SetTransform(f func(string) string) {
+	h.stateLock.Lock()
+	h.transform = f
+	h.stateLock.Unlock()
+}
+
+// ClearTransform removes the transform set by SetTransform.
+func (h *Handle) ClearTransform() {
+	h.SetTransform(nil)
+}
+
+// prepare returns the source to feed the next wave from, with the items
+// reordered and filtered as configured. It is only called from the feeding
+// goroutine.
+func (h *Handle) prepare(src Source) Source {
+	h.stateLock.RLock()
+	shuffle, seed, dedup, filter := h.shuffle, h.shuffleSeed, h.dedup, h.filter
+	h.stateLock.RUnlock()
+
+	if s, ok := src.(*sliceSource); ok && shuffle {
+		src = h.shuffled(s, seed)
+	}
+	src = h.startCheckpoint(src)
+	h.skipped.Store(0)
+	if filter != nil {
+		src = &filterSource{src: src, filter: filter, skipped: &h.skipped}
+	}
+	h.duplicates.Store(0)
+	if dedup {
+		src = &dedupSource{src: src, removed: &h.duplicates}
+	}
+	return src
+}
+
+func (h *Handle) shuffled(s *sliceSource, seed *int64) Source {
+	rng := h.rng
+	if seed != nil {
+		rng = rand.New(rand.NewSource(*seed))
+	} else if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+		h.rng = rng
+	}
+	vals := append([]string(nil), s.vals[s.next:]...)
+	rng.Shuffle(len(vals), func(i, j int) {
+		vals[i], vals[j] = vals[j], vals[i]
+	})
+	return SliceSource(vals)
+}
+
+// Number of distinct items a dedupSource tracks in a slice before it switches
+// to a map.
+const dedupSliceMax = 16
+
+// dedupSource skips items that it has returned before.
+type dedupSource struct {
+	src     Source
+	seen    []string            // Used while there are few items
+	seenMap map[string]struct{} // Used once seen would grow too large
+	removed *atomic.Int64
+}
+
+func (s *dedupSource) Next() (string, bool) {
+	for {
+		val, ok := s.src.Next()
+		if !ok {
+			return "", false
+		}
+		if !s.add(val) {
+			s.removed.Add(1)
+			skipped(s.src, val)
+			continue
+		}
+		return val, true
+	}
+}
+
+func (s *dedupSource) skip(val string) { skipped(s.src, val) }
+
+// add records val, returning false if it was seen before.
+func (s *dedupSource) add(val string) bool {
+	if s.seenMap == nil {
+		if slices.Contains(s.seen, val) {
+			return false
+		}
+		if len(s.seen) < dedupSliceMax {
+			s.seen = append(s.seen, val)
+			return true
+		}
+		s.seenMap = make(map[string]struct{}, 2*dedupSliceMax)
+		for _, v := range s.seen {
+			s.seenMap[v] = struct{}{}
+		}
+		s.seen = nil
+	}
+	if _, ok := s.seenMap[val]; ok {
+		return false
+	}
+	s.seenMap[val] = struct{}{}
+	return true
+}
+
+// filterSource skips items rejected by filter.
+type filterSource struct {
+	src     Source
+	filter  func(string) bool
+	skipped *atomic.Int64
+}
+
+func (s *filterSource) Next() (string, bool) {
+	for {
+		val, ok := s.src.Next()
+		if !ok || s.filter(val) {
+			return val, ok
+		}
+		s.skipped.Add(1)
+		skipped(s.src, val)
+	}
+}
+
+func (s *filterSource) skip(val string) { skipped(s.src, val) }
+
+// AtomicSwapVals replaces the items of a wave created by Once or Continuous
+// and returns the previous ones. The new items are used from the next wave on,
+// so the host list of a Continuous wave can be rotated without stopping it. It
+// has no effect on other waves and returns nil for them.
+func (h *Handle) AtomicSwapVals(newVals []string) []string {
+	if h.vals.Load() == nil {
+		return nil
+	}
+	return *h.vals.Swap(&newVals)
+}
+
+// setVals makes every wave of h take its items from vals, or from the items
+// given to AtomicSwapVals later. It must be called before h is started.
+func (h *Handle) setVals(vals []string) {
+	h.vals.Store(&vals)
+	h.newSource = func() Source { return SliceSource(*h.vals.Load()) }
+}
+
+// Materialize returns the items the next wave would pass to the callback,
+// after SetFilter, SetDeduplication and SetTransform, without processing any
+// of them. Items whose transform panics are left out. The order is that of
+// the items given to Once or Continuous, even if SetShuffle is on. It returns
+// nil for waves that don't take their items from a slice.
+func (h *Handle) Materialize() []string {
+	vals := h.vals.Load()
+	if vals == nil {
+		return nil
+	}
+	h.stateLock.RLock()
+	dedup, filter, transform := h.dedup, h.filter, h.transform
+	h.stateLock.RUnlock()
+
+	var discarded atomic.Int64
+	src := SliceSource(*vals)
+	if filter != nil {
+		src = &filterSource{src: src, filter: filter, skipped: &discarded}
+	}
+	if dedup {
+		src = &dedupSource{src: src, removed: &discarded}
+	}
+	var items []string
+	for val, ok := src.Next(); ok; val, ok = src.Next() {
+		if transform != nil {
+			var err error
+			val, err = transformed(transform, val)
+			if err != nil {
+				continue
+			}
+		}
+		items = append(items, val)
+	}
+	return items
+}
+
+// SetDryRun makes workers count items instead of passing them to the callback
+// chain, to check a filter or transform before a real sweep. Items counted in
+// a dry run are not reported as processed. The setting is read for every
+// item, so it takes effect immediately.
+func (h *Handle) SetDryRun(dryRun bool) {
+	h.dryRun.Store(dryRun)
+}
+
+// DryRunCount returns how many items the current or most recent wave counted
+// because of SetDryRun.
+func (h *Handle) DryRunCount() int {
+	return int(h.dryRunCount.Load())
+}
+
+// SetIterationDeduplication makes workers skip an item that the current wave
+// has already processed, as a safeguard against an item being delivered twice
+// by a bug. Unlike SetDeduplication, which removes duplicates as items are
+// taken from the source, it checks items as they reach the workers, and it
+// counts them with DuplicateInIterationCount. Every wave starts afresh.
+func (h *Handle) SetIterationDeduplication(dedup bool) {
+	h.seenLock.Lock()
+	if !dedup {
+		h.seen = nil
+	} else if h.seen == nil {
+		h.seen = map[string]struct{}{}
+	}
+	h.seenLock.Unlock()
+}
+
+// DuplicateInIterationCount returns how many items the current or most recent
+// wave skipped because of SetIterationDeduplication.
+func (h *Handle) DuplicateInIterationCount() int {
+	return int(h.seenDups.Load())
+}
+
+// seenInWave records val as processed by the current wave, and reports
+// whether it already was.
+func (h *Handle) seenInWave(val string) bool {
+	h.seenLock.Lock()
+	defer h.seenLock.Unlock()
+	if h.seen == nil {
+		return false
+	}
+	if _, ok := h.seen[val]; ok {
+		h.seenDups.Add(1)
+		return true
+	}
+	h.seen[val] = struct{}{}
+	return false
+}
+
+func (h *Handle) resetSeen() {
+	h.seenDups.Store(0)
+	h.seenLock.Lock()
+	if h.seen != nil {
+		clear(h.seen)
+	}
+	h.seenLock.Unlock()
+}
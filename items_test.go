@@ -0,0 +1,311 @@
+package wave
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// record returns a callback that appends items to *order.
+func record(order *[]string) func(string) {
+	lock := sync.Mutex{}
+	return func(val string) {
+		lock.Lock()
+		*order = append(*order, val)
+		lock.Unlock()
+	}
+}
+
+func TestShuffleSeed(t *testing.T) {
+	var a, b []string
+	for _, order := range []*[]string{&a, &b} {
+		w := Once(1, FakeEndpoints(), record(order))
+		w.SetShuffleSeed(42)
+		w.Finish()
+	}
+	if !slices.Equal(a, b) {
+		t.Error("Expected identical orders, got", a, b)
+	}
+	if slices.Equal(a, FakeEndpoints()) {
+		t.Error("Expected a shuffled order, got", a)
+	}
+}
+
+func TestShuffleContinuous(t *testing.T) {
+	var order []string
+	var waves [][]string
+	var w *Handle
+	w = Continuous(1, FakeEndpoints(), record(&order))
+	w.SetShuffle(true)
+	w.AfterEach(func() {
+		waves = append(waves, order)
+		order = nil
+		if len(waves) == 2 {
+			go w.Finish()
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	if slices.Equal(waves[0], waves[1]) {
+		t.Error("Expected different orders, got", waves[0], waves[1])
+	}
+	for _, wave := range waves[:2] {
+		sorted := slices.Clone(wave)
+		slices.Sort(sorted)
+		if !slices.Equal(sorted, FakeEndpoints()) {
+			t.Error("Expected every item once, got", wave)
+		}
+	}
+}
+
+func TestDeduplication(t *testing.T) {
+	var vals []string
+	for i := 0; i < 80; i++ {
+		vals = append(vals, strconv.Itoa(i))
+	}
+	for i := 0; i < 20; i++ {
+		vals = append(vals, strconv.Itoa(i*3))
+	}
+
+	var count atomic.Int32
+	var removed []int
+	var w *Handle
+	w = Continuous(10, vals, func(string) {
+		count.Add(1)
+	})
+	w.SetDeduplication(true)
+	w.AfterEach(func() {
+		if removed = append(removed, w.DuplicatesRemoved()); len(removed) == 2 {
+			go w.Finish()
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	if n := count.Load(); n != int32(80*len(removed)) {
+		t.Error("Expected", 80*len(removed), "got", n)
+	}
+	for _, n := range removed {
+		if n != 20 {
+			t.Error("Expected 20 duplicates per wave, got", n)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var vals []string
+	for i := 0; i < 100; i++ {
+		vals = append(vals, strconv.Itoa(i))
+	}
+	var count atomic.Int32
+	w := Once(10, vals, func(string) {
+		count.Add(1)
+	})
+	even := true
+	w.SetFilter(func(string) bool {
+		even = !even
+		return even
+	})
+	w.Finish()
+
+	if n := count.Load(); n != 50 {
+		t.Error("Expected 50, got", n)
+	}
+	if n := w.SkippedCount(); n != 50 {
+		t.Error("Expected 50 skipped, got", n)
+	}
+}
+
+func TestClearFilter(t *testing.T) {
+	var count atomic.Int32
+	var w *Handle
+	waves := 0
+	w = Continuous(10, FakeEndpoints(), func(string) {
+		count.Add(1)
+	})
+	w.SetFilter(func(string) bool { return false })
+	w.AfterEach(func() {
+		if waves++; waves == 1 {
+			if n := count.Load(); n != 0 {
+				t.Error("Expected 0 with filter, got", n)
+			}
+			w.ClearFilter()
+		} else if waves == 2 {
+			go w.Finish()
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	if n := count.Load(); n < numPorts {
+		t.Error("Expected at least", numPorts, "without filter, got", n)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	var got []string
+	w := Once(1, FakeEndpoints(), record(&got))
+	w.SetTransform(func(val string) string {
+		return "http://localhost" + val
+	})
+	w.Finish()
+
+	for i, val := range got {
+		if want := "http://localhost" + FakeEndpoints()[i]; val != want {
+			t.Error("Expected", want, "got", val)
+		}
+	}
+	if len(got) != numPorts {
+		t.Error("Expected", numPorts, "got", len(got))
+	}
+}
+
+func TestTransformPanic(t *testing.T) {
+	var failed atomic.Int32
+	var called atomic.Int32
+	w := Once(2, FakeEndpoints(), func(string) {
+		called.Add(1)
+	})
+	w.SetTransform(func(val string) string {
+		if val == ":3000" {
+			panic("bad item")
+		}
+		return val
+	})
+	w.Tap(func(val string, err error, dur time.Duration) {
+		if (err != nil) != (val == ":3000") {
+			t.Error("Unexpected error for", val, err)
+		}
+		if err != nil {
+			failed.Add(1)
+		}
+	})
+	w.Finish()
+
+	if failed.Load() != 1 || called.Load() != numPorts-1 {
+		t.Error("Expected one failure, got", failed.Load(), "failures and", called.Load(), "calls")
+	}
+}
+
+func TestAtomicSwapVals(t *testing.T) {
+	var lock sync.Mutex
+	seen := map[string]int64{}
+	var w *Handle
+	w = Continuous(3, FakeEndpoints(), func(val string) {
+		lock.Lock()
+		seen[val] = w.waves.Load()
+		lock.Unlock()
+	})
+	var old []string
+	w.AfterEach(func() {
+		switch w.waves.Load() {
+		case 1:
+			old = w.AtomicSwapVals([]string{"a", "b"})
+		case 3:
+			go w.Finish()
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	if !slices.Equal(old, FakeEndpoints()) {
+		t.Error("Expected the original vals back, got", old)
+	}
+	for val, wave := range seen {
+		if (val == "a" || val == "b") != (wave > 1) {
+			t.Error("Expected", val, "in the other waves, but it was last seen in wave", wave)
+		}
+	}
+	if seen["a"] == 0 || seen["b"] == 0 {
+		t.Error("Expected the new vals to be processed, got", seen)
+	}
+	fromSource := OnceFromSource(1, SliceSource(nil), func(string) {})
+	if got := fromSource.AtomicSwapVals([]string{"x"}); got != nil {
+		t.Error("Expected nil for a wave over a Source, got", got)
+	}
+	fromSource.Finish()
+}
+
+func TestMaterialize(t *testing.T) {
+	w := Once(1, []string{"a", "b", "a", "skip", "c", "boom"}, func(string) {
+		t.Error("Expected Materialize not to process items")
+	})
+	w.SetDeduplication(true)
+	w.SetFilter(func(val string) bool { return val != "skip" })
+	w.SetTransform(func(val string) string {
+		if val == "boom" {
+			panic(val)
+		}
+		return "host-" + val
+	})
+
+	if got, want := w.Materialize(), []string{"host-a", "host-b", "host-c"}; !slices.Equal(got, want) {
+		t.Error("Expected", want, "got", got)
+	}
+	if n := w.SkippedCount() + w.DuplicatesRemoved(); n != 0 {
+		t.Error("Expected Materialize not to count skipped items, got", n)
+	}
+	if got := OnceFromChan(1, nil, func(string) {}).Materialize(); got != nil {
+		t.Error("Expected nil for a wave over a channel, got", got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	vals := make([]string, 50)
+	for i := range vals {
+		vals[i] = strconv.Itoa(i)
+	}
+	var calls atomic.Int64
+	w := Once(5, vals, func(string) { calls.Add(1) })
+	w.SetFilter(func(val string) bool { return val != "0" })
+	w.SetDryRun(true)
+	w.Finish()
+
+	if n := calls.Load(); n != 0 {
+		t.Error("Expected the callback not to be called, got", n, "calls")
+	}
+	if n := w.DryRunCount(); n != 49 {
+		t.Error("Expected 49 items counted, got", n)
+	}
+	if n := w.CompletedCount(); n != 0 {
+		t.Error("Expected no items processed, got", n)
+	}
+}
+
+func TestIterationDeduplication(t *testing.T) {
+	vals := append(FakeEndpoints(), ":3003") // A bug delivers :3003 twice
+	var lock sync.Mutex
+	counts := map[string]int{}
+	var w *Handle
+	w = Continuous(3, vals, func(val string) {
+		lock.Lock()
+		counts[val]++
+		lock.Unlock()
+	})
+	w.SetIterationDeduplication(true)
+	var dups []int
+	w.AfterEach(func() {
+		dups = append(dups, w.DuplicateInIterationCount())
+		if w.waves.Load() == 2 {
+			go w.Finish()
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	waves := int(w.waves.Load())
+	for _, val := range FakeEndpoints() {
+		if counts[val] != waves {
+			t.Error("Expected", val, "once per wave, got", counts[val], "in", waves, "waves")
+		}
+	}
+	for _, n := range dups {
+		if n != 1 {
+			t.Error("Expected 1 duplicate per wave, got", dups)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package wave
+
+// Limit caps the number of items the wave processes across all waves at n,
+// which is useful to bound the cost of a Continuous wave. Once the quota is
+// used up, the wave is interrupted. Limit returns h so that it can be chained
+// onto the constructor. A limit of 0 or less removes the cap.
+func (h *Handle) Limit(n int) *Handle {
+	if n < 0 {
+		n = 0
+	}
+	h.limit.Store(int64(n))
+	return h
+}
+
+// Remaining returns how many more items the wave may process under Limit, or
+// -1 if there is no limit.
+func (h *Handle) Remaining() int {
+	limit := h.limit.Load()
+	if limit == 0 {
+		return -1
+	}
+	return int(max(limit-h.limitUsed.Load(), 0))
+}
+
+// takeQuota reserves an item under Limit. When the quota is used up, it
+// interrupts the wave and returns false.
+func (h *Handle) takeQuota() bool {
+	limit := h.limit.Load()
+	if limit == 0 {
+		return true
+	}
+	if h.limitUsed.Add(1) <= limit {
+		return true
+	}
+	h.limitUsed.Add(-1)
+	h.interrupt.Do(func() {
+		h.debug("close", "interruptChan", "reason", "limit reached")
+		close(h.interruptChan)
+	})
+	return false
+}
@@ -0,0 +1,37 @@
+package wave
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestLimit(t *testing.T) {
+	var calls atomic.Int64
+	w := Continuous(3, FakeEndpoints(), func(string) { calls.Add(1) }).Limit(25)
+	if n := w.Remaining(); n != 25 {
+		t.Error("Expected 25 remaining, got", n)
+	}
+	w.Start()
+	w.Wait()
+
+	if n := calls.Load(); n != 25 {
+		t.Error("Expected 25 items across waves, got", n)
+	}
+	if n := w.Remaining(); n != 0 {
+		t.Error("Expected 0 remaining, got", n)
+	}
+	if s := w.Snapshot().State; s != StateInterrupted {
+		t.Error("Expected the wave to be interrupted, got", s)
+	}
+}
+
+func TestNoLimit(t *testing.T) {
+	w := Once(1, FakeEndpoints(), func(string) {}).Limit(5).Limit(0)
+	w.Finish()
+	if n := w.Remaining(); n != -1 {
+		t.Error("Expected -1 remaining without a limit, got", n)
+	}
+	if n := w.CompletedCount(); n != numPorts {
+		t.Error("Expected", numPorts, "items, got", n)
+	}
+}
@@ -0,0 +1,77 @@
+package wave
+
+import (
+	"context"
+	"time"
+)
+
+// DistributedLock is a lock shared by several processes, for example backed
+// by Redis or etcd, see WithDistributedLock.
+type DistributedLock interface {
+	// TryLock takes the lock on key for at most ttl without waiting for it,
+	// and reports whether it was taken.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Unlock releases the lock on key.
+	Unlock(ctx context.Context, key string) error
+}
+
+const (
+	defaultLockKey   = "wave"      // Key of the lock of unnamed waves
+	lockTTL          = time.Minute // How long a wave may hold its lock
+	defaultLockRetry = time.Second // How long a Continuous wave waits after losing the lock
+)
+
+// WithDistributedLock makes the wave take lock before each wave, so that when
+// several replicas run the same wave, only one of them runs each wave. The
+// lock is keyed by the name of the wave, see Registry.Register, or "wave" for
+// unnamed waves, and held until the wave ends, for at most a minute. A replica
+// that does not get the lock skips the wave: a Continuous wave tries again a
+// second later, and a Once wave does not run at all. An error from lock counts
+// as not getting it.
+func WithDistributedLock(lock DistributedLock) Option {
+	return func(c *handleConfig) { c.lock = lock }
+}
+
+// lockWave takes the distributed lock for the next wave, if any. It returns
+// false if the wave should be skipped, and otherwise a function that releases
+// the lock.
+func (h *Handle) lockWave() (unlock func(), ok bool) {
+	if h.lock == nil {
+		return func() {}, true
+	}
+	ctx := h.watchCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	key := h.waveName()
+	if key == "" {
+		key = defaultLockKey
+	}
+	locked, err := h.lock.TryLock(ctx, key, lockTTL)
+	if err != nil || !locked {
+		h.debug("skip", "wave", "reason", "lock not taken", "key", key, "err", err)
+		return nil, false
+	}
+	return func() {
+		if err := h.lock.Unlock(ctx, key); err != nil {
+			h.debug("fail", "unlock", "key", key, "err", err)
+		}
+	}, true
+}
+
+// sleep waits for d. It returns false if h was interrupted or finished first.
+func (h *Handle) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-h.interruptChan:
+		return false
+	case <-h.finishChan:
+		return false
+	}
+}
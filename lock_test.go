@@ -0,0 +1,109 @@
+package wave
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLock is a DistributedLock shared by the replicas of a test.
+type fakeLock struct {
+	held    map[string]bool
+	keys    []string // Keys taken so far
+	unlocks int
+	err     error
+	lock    sync.Mutex // Guards all fields
+}
+
+func (l *fakeLock) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.err != nil {
+		return false, l.err
+	}
+	if l.held[key] {
+		return false, nil
+	}
+	if l.held == nil {
+		l.held = map[string]bool{}
+	}
+	l.held[key] = true
+	l.keys = append(l.keys, key)
+	return true, nil
+}
+
+func (l *fakeLock) Unlock(ctx context.Context, key string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.held, key)
+	l.unlocks++
+	return nil
+}
+
+func TestWithDistributedLock(t *testing.T) {
+	lock := &fakeLock{}
+	var calls atomic.Int32
+	w := OnceWithOptions(FakeEndpoints(), func(string) { calls.Add(1) }, WithDistributedLock(lock))
+	r := &Registry{}
+	r.Register("pinger", w)
+	w.Finish()
+
+	if n := calls.Load(); n != numPorts {
+		t.Error("Expected every item to be processed, got", n)
+	}
+	if len(lock.keys) != 1 || lock.keys[0] != "pinger" || lock.unlocks != 1 {
+		t.Error("Expected the lock to be taken and released once under the wave name, got", lock.keys, lock.unlocks)
+	}
+}
+
+func TestWithDistributedLockHeld(t *testing.T) {
+	lock := &fakeLock{held: map[string]bool{defaultLockKey: true}} // Held by another replica
+	var calls atomic.Int32
+	w := OnceWithOptions(FakeEndpoints(), func(string) { calls.Add(1) }, WithDistributedLock(lock))
+	w.Finish()
+	if n := calls.Load(); n != 0 {
+		t.Error("Expected the wave to be skipped, got", n, "calls")
+	}
+
+	lock.err = errors.New("lock unreachable")
+	delete(lock.held, defaultLockKey)
+	w = OnceWithOptions(FakeEndpoints(), func(string) { calls.Add(1) }, WithDistributedLock(lock))
+	w.Finish()
+	if n := calls.Load(); n != 0 {
+		t.Error("Expected the wave to be skipped when the lock fails, got", n, "calls")
+	}
+}
+
+func TestWithDistributedLockContinuousRetry(t *testing.T) {
+	lock := &fakeLock{held: map[string]bool{defaultLockKey: true}}
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithMaxIterations(2), WithDistributedLock(lock))
+	w.lockRetry = time.Millisecond
+	w.Start()
+
+	time.Sleep(20 * time.Millisecond)
+	if n := w.waves.Load(); n != 0 {
+		t.Error("Expected no waves while another replica holds the lock, got", n)
+	}
+	lock.Unlock(context.Background(), defaultLockKey)
+	if !w.waitTimeout(5 * time.Second) {
+		w.Interrupt()
+		t.Fatal("Expected the wave to run once the lock is free")
+	}
+	if n := w.waves.Load(); n != 2 {
+		t.Error("Expected 2 waves, got", n)
+	}
+}
+
+func TestWithDistributedLockInterrupt(t *testing.T) {
+	lock := &fakeLock{held: map[string]bool{defaultLockKey: true}}
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithDistributedLock(lock))
+	w.lockRetry = time.Hour
+	w.Start()
+	w.Interrupt()
+	if !w.waitTimeout(time.Second) {
+		t.Fatal("Expected Interrupt to stop a wave waiting for its lock")
+	}
+}
@@ -0,0 +1,129 @@
+package wave
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MetricsSnapshot holds the counters and gauges of a wave at one point in
+// time, for export to a monitoring system.
+type MetricsSnapshot struct {
+	WaveName        string // Used as the wave label, omitted if empty
+	WaveID          string // ID of the current wave, exported on wave_info
+	State           State
+	Waves           int64 // Waves started so far
+	Processed       int64 // Items processed by all waves
+	Errors          int64 // Items that failed in all waves
+	ProcessedInWave int64 // Items processed by the current or last wave
+	Skipped         int64 // Items skipped by SetFilter in the current or last wave
+	Queued          int   // Items waiting for a worker
+	InFlight        int   // Items whose callback is running
+	Concurrency     int   // Current number of workers
+}
+
+// Metrics returns the current metrics of the wave. It is safe to call at any
+// time.
+func (h *Handle) Metrics() MetricsSnapshot {
+	return MetricsSnapshot{
+		WaveName:        h.waveName(),
+		WaveID:          h.ID(),
+		State:           h.state(),
+		Waves:           h.waves.Load(),
+		Processed:       h.processed.Load(),
+		Errors:          h.errorCount.Load(),
+		ProcessedInWave: h.waveProcessed.Load(),
+		Skipped:         int64(h.SkippedCount()),
+		Queued:          h.QueuedCount(),
+		InFlight:        h.InFlightCount(),
+		Concurrency:     h.CurrentConcurrency(),
+	}
+}
+
+// MarshalPrometheus writes m to w in the Prometheus text exposition format,
+// with HELP and TYPE comments, so that it can be served from a plain HTTP
+// handler without a Prometheus client library. Samples are labelled with the
+// wave name, if any; the ID of the current wave is exported on the wave_info
+// gauge, so that counters keep one series across waves. To serve several waves
+// from one endpoint, use Registry.MarshalPrometheus, since the output of
+// several calls cannot be concatenated.
+func (m MetricsSnapshot) MarshalPrometheus(w io.Writer) error {
+	return writePrometheus(w, []MetricsSnapshot{m})
+}
+
+// prometheusMetrics are the metrics written by writePrometheus, besides
+// wave_state.
+var prometheusMetrics = []struct {
+	name, kind, help string
+	value            func(MetricsSnapshot) int64
+}{
+	{"wave_waves_total", "counter", "Waves started.", func(m MetricsSnapshot) int64 { return m.Waves }},
+	{"wave_items_processed_total", "counter", "Items processed by all waves.", func(m MetricsSnapshot) int64 { return m.Processed }},
+	{"wave_items_failed_total", "counter", "Items that failed in all waves.", func(m MetricsSnapshot) int64 { return m.Errors }},
+	{"wave_items_processed_in_wave", "gauge", "Items processed by the current or last wave.", func(m MetricsSnapshot) int64 { return m.ProcessedInWave }},
+	{"wave_items_skipped_in_wave", "gauge", "Items skipped by the filter in the current or last wave.", func(m MetricsSnapshot) int64 { return m.Skipped }},
+	{"wave_items_queued", "gauge", "Items waiting for a worker.", func(m MetricsSnapshot) int64 { return int64(m.Queued) }},
+	{"wave_items_in_flight", "gauge", "Items whose callback is running.", func(m MetricsSnapshot) int64 { return int64(m.InFlight) }},
+	{"wave_concurrency", "gauge", "Current number of workers.", func(m MetricsSnapshot) int64 { return int64(m.Concurrency) }},
+}
+
+// writePrometheus writes the samples of every snapshot under a single HELP
+// and TYPE comment per metric. The wave ID changes with every wave, so it is
+// only exported on wave_info, to keep the series of the samples stable.
+func writePrometheus(w io.Writer, snaps []MetricsSnapshot) error {
+	labels := make([]string, len(snaps))
+	for i, m := range snaps {
+		if m.WaveName != "" {
+			labels[i] = `wave="` + escapeLabel(m.WaveName) + `"`
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, metric := range prometheusMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for i, m := range snaps {
+			if labels[i] == "" {
+				fmt.Fprintf(bw, "%s %d\n", metric.name, metric.value(m))
+			} else {
+				fmt.Fprintf(bw, "%s{%s} %d\n", metric.name, labels[i], metric.value(m))
+			}
+		}
+	}
+
+	// The state is exported as one series per state, set to 1 for the
+	// current one, which is the usual way to expose an enum.
+	fmt.Fprint(bw, "# HELP wave_state Lifecycle state of the wave.\n# TYPE wave_state gauge\n")
+	for i, m := range snaps {
+		for s := StateIdle; s <= StateInterrupted; s++ {
+			val := 0
+			if s == m.State {
+				val = 1
+			}
+			stateLabels := `state="` + s.String() + `"`
+			if labels[i] != "" {
+				stateLabels = labels[i] + "," + stateLabels
+			}
+			fmt.Fprintf(bw, "wave_state{%s} %d\n", stateLabels, val)
+		}
+	}
+
+	fmt.Fprint(bw, "# HELP wave_info Identity of the current or last wave.\n# TYPE wave_info gauge\n")
+	for i, m := range snaps {
+		if m.WaveID == "" {
+			continue
+		}
+		infoLabels := `wave_id="` + escapeLabel(m.WaveID) + `"`
+		if labels[i] != "" {
+			infoLabels = labels[i] + "," + infoLabels
+		}
+		fmt.Fprintf(bw, "wave_info{%s} 1\n", infoLabels)
+	}
+	return bw.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,84 @@
+package wave
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	w := once(2, SliceSource(FakeEndpoints()), func(val string) error {
+		if val == ":3003" {
+			return errFault
+		}
+		return nil
+	})
+	w.Finish()
+
+	m := w.Metrics()
+	if m.State != StateStopped || m.Waves != 1 || m.Processed != numPorts || m.Errors != 1 || m.Concurrency != 2 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+	if m.WaveID == "" || m.WaveID != w.ID() {
+		t.Error("Expected the ID of the last wave, got", m.WaveID)
+	}
+}
+
+func TestMarshalPrometheus(t *testing.T) {
+	m := MetricsSnapshot{WaveName: `pinger "eu"`, WaveID: "abc", State: StateRunning, Waves: 3, Processed: 42, Errors: 2, Concurrency: 4}
+	buf := &bytes.Buffer{}
+	if err := m.MarshalPrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		"# HELP wave_items_processed_total Items processed by all waves.",
+		"# TYPE wave_items_processed_total counter",
+		`wave_items_processed_total{wave="pinger \"eu\""} 42`,
+		`wave_items_failed_total{wave="pinger \"eu\""} 2`,
+		"# TYPE wave_concurrency gauge",
+		`wave_concurrency{wave="pinger \"eu\""} 4`,
+		`wave_state{wave="pinger \"eu\"",state="running"} 1`,
+		`wave_state{wave="pinger \"eu\"",state="idle"} 0`,
+		"# TYPE wave_info gauge",
+		`wave_info{wave="pinger \"eu\"",wave_id="abc"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, out)
+		}
+	}
+
+	// Every sample must follow the HELP and TYPE of its metric.
+	typed := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if fields := strings.Fields(line); fields[0] == "#" {
+			if fields[1] == "TYPE" {
+				typed[fields[2]] = true
+			}
+			continue
+		}
+		name := strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0]
+		if !typed[name] {
+			t.Error("Sample before its TYPE comment:", line)
+		}
+	}
+
+	buf.Reset()
+	if err := (MetricsSnapshot{Waves: 1}).MarshalPrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\nwave_waves_total 1\n") {
+		t.Error("Expected unlabeled samples without a wave name, got:\n", buf.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errFault }
+
+func TestMarshalPrometheusWriteError(t *testing.T) {
+	if err := (MetricsSnapshot{}).MarshalPrometheus(failingWriter{}); !errors.Is(err, errFault) {
+		t.Error("Expected the write error, got", err)
+	}
+}
@@ -0,0 +1,110 @@
+package wave
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// CallbackMiddleware wraps the callback of a wave, or the next middleware in
+// the chain. The returned function may inspect or alter the item, the error,
+// or both. NewFaultInjector returns a CallbackMiddleware.
+type CallbackMiddleware func(next func(string) error) func(string) error
+
+// Use wraps the callback of the wave in middleware. Middleware registered
+// first runs outermost. Can be called multiple times, including while the
+// wave is running, in which case it takes effect from the next item.
+func (h *Handle) Use(m ...CallbackMiddleware) {
+	h.funcsLock.Lock()
+	h.middleware = append(h.middleware, m...)
+	chain := h.callback
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		chain = h.middleware[i](chain)
+	}
+	h.chain = chain
+	h.funcsLock.Unlock()
+}
+
+// LoggingMiddleware logs every item with its duration and error to logger,
+// or to the standard logger if logger is nil.
+func LoggingMiddleware(logger *log.Logger) CallbackMiddleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next func(string) error) func(string) error {
+		return func(val string) error {
+			began := time.Now()
+			err := next(val)
+			if err != nil {
+				logger.Printf("wave: %s failed after %s: %v", val, time.Since(began), err)
+			} else {
+				logger.Printf("wave: %s done in %s", val, time.Since(began))
+			}
+			return err
+		}
+	}
+}
+
+// TracingMiddleware calls start before each item and the function it returns
+// with the error of the item afterwards, so that items can be recorded as
+// spans by any tracing library.
+func TracingMiddleware(start func(val string) (end func(err error))) CallbackMiddleware {
+	return func(next func(string) error) func(string) error {
+		return func(val string) error {
+			end := start(val)
+			err := next(val)
+			end(err)
+			return err
+		}
+	}
+}
+
+// RetryMiddleware calls the rest of the chain up to attempts times, but at
+// least once, until it succeeds, sleeping for backoff between attempts. The
+// last error is returned, wrapped so that SetDLQ can report the number of
+// attempts.
+func RetryMiddleware(attempts int, backoff time.Duration) CallbackMiddleware {
+	attempts = max(attempts, 1)
+	return func(next func(string) error) func(string) error {
+		return func(val string) error {
+			var err error
+			for i := 0; i < attempts; i++ {
+				if i > 0 {
+					time.Sleep(backoff)
+				}
+				if err = next(val); err == nil {
+					return nil
+				}
+			}
+			return &retryError{err: err, attempts: attempts}
+		}
+	}
+}
+
+// TimeIt wraps fn to measure how long it takes for every item. The returned
+// durations function reports the duration of every item processed so far,
+// keyed by item, so it is best called after the wave completes. An item
+// processed more than once keeps its latest duration.
+func TimeIt(fn func(string) error) (wrapped func(string) error, durations func() map[string]time.Duration) {
+	times := map[string]time.Duration{}
+	lock := sync.Mutex{} // Guards times
+	wrapped = func(val string) error {
+		began := time.Now()
+		err := fn(val)
+		d := time.Since(began)
+		lock.Lock()
+		times[val] = d
+		lock.Unlock()
+		return err
+	}
+	durations = func() map[string]time.Duration {
+		lock.Lock()
+		defer lock.Unlock()
+		out := make(map[string]time.Duration, len(times))
+		for val, d := range times {
+			out[val] = d
+		}
+		return out
+	}
+	return wrapped, durations
+}
@@ -0,0 +1,142 @@
+package wave
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUseOrder(t *testing.T) {
+	var order []string
+	lock := sync.Mutex{}
+	record := func(name string) CallbackMiddleware {
+		return func(next func(string) error) func(string) error {
+			return func(val string) error {
+				lock.Lock()
+				order = append(order, name)
+				lock.Unlock()
+				return next(val)
+			}
+		}
+	}
+
+	w := Once(1, []string{"host"}, func(string) {
+		lock.Lock()
+		order = append(order, "callback")
+		lock.Unlock()
+	})
+	w.Use(record("first"), record("second"))
+	w.Use(record("third"))
+	w.Finish()
+
+	if got := strings.Join(order, ","); got != "first,second,third,callback" {
+		t.Error("Unexpected order", got)
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	var failed atomic.Int32
+	flaky := func(next func(string) error) func(string) error {
+		return func(val string) error {
+			if calls.Add(1)%3 != 0 {
+				return errFault
+			}
+			return next(val)
+		}
+	}
+
+	w := Once(1, FakeEndpoints(), func(string) {})
+	w.Use(RetryMiddleware(3, time.Microsecond), flaky)
+	w.Tap(func(val string, err error, dur time.Duration) {
+		if err != nil {
+			failed.Add(1)
+		}
+	})
+	w.Finish()
+
+	if n := failed.Load(); n != 0 {
+		t.Error("Expected every item to succeed on its third attempt, got", n, "failures")
+	}
+	if n := calls.Load(); n != 3*numPorts {
+		t.Error("Expected", 3*numPorts, "attempts, got", n)
+	}
+}
+
+func TestRetryMiddlewareNoAttempts(t *testing.T) {
+	for _, attempts := range []int{0, -1} {
+		calls := 0
+		retry := RetryMiddleware(attempts, time.Microsecond)(func(string) error {
+			calls++
+			return errFault
+		})
+		if err := retry("a"); !errors.Is(err, errFault) {
+			t.Error("Expected the error of the only attempt, got", err)
+		}
+		if calls != 1 {
+			t.Error("Expected", attempts, "attempts to call the chain once, got", calls)
+		}
+	}
+}
+
+func TestLoggingAndTracingMiddleware(t *testing.T) {
+	buf := bytes.Buffer{}
+	var spans atomic.Int32
+	w := Once(1, []string{"good", "bad"}, func(string) {})
+	w.Use(
+		LoggingMiddleware(log.New(&buf, "", 0)),
+		TracingMiddleware(func(val string) func(error) {
+			return func(err error) {
+				if (val == "bad") != (err != nil) {
+					t.Error("Unexpected error for", val, err)
+				}
+				spans.Add(1)
+			}
+		}),
+		NewFaultInjector(0, 0, 0, nil),
+		func(next func(string) error) func(string) error {
+			return func(val string) error {
+				if val == "bad" {
+					return errFault
+				}
+				return next(val)
+			}
+		},
+	)
+	w.Finish()
+
+	if n := spans.Load(); n != 2 {
+		t.Error("Expected 2 spans, got", n)
+	}
+	if out := buf.String(); !strings.Contains(out, "good done") || !strings.Contains(out, "bad failed") {
+		t.Error("Unexpected log output", out)
+	}
+}
+
+func TestTimeIt(t *testing.T) {
+	callback, durations := TimeIt(func(val string) error {
+		if val == ":3004" {
+			time.Sleep(20 * time.Millisecond)
+			return errFault
+		}
+		return nil
+	})
+	w := once(4, SliceSource(FakeEndpoints()), callback)
+	w.Finish()
+
+	times := durations()
+	if len(times) != numPorts {
+		t.Fatal("Expected", numPorts, "durations, got", len(times))
+	}
+	if d := times[":3004"]; d < 20*time.Millisecond {
+		t.Error("Expected the slow item to take at least 20ms, got", d)
+	}
+	if errs := w.Errors(); len(errs) != 1 || errs[0].Val != ":3004" {
+		t.Error("Expected the error to be passed through, got", errs)
+	}
+}
@@ -0,0 +1,183 @@
+package wave
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Option configures a wave created by OnceWithOptions or
+// ContinuousWithOptions.
+type Option func(*handleConfig)
+
+type handleConfig struct {
+	concurrency   int
+	ctx           context.Context
+	timeout       time.Duration
+	rateLimit     float64 // Items per second, 0 for no limit
+	maxIterations int
+	retries       int
+	retryBackoff  time.Duration
+	debug         *slog.Logger
+	recovery      *panicRecovery
+	tapTimeout    time.Duration
+	circuit       *waveCircuitBreaker
+	lock          DistributedLock
+}
+
+// WithConcurrency sets the number of workers. The default is 1.
+func WithConcurrency(n int) Option {
+	return func(c *handleConfig) { c.concurrency = n }
+}
+
+// WithContext interrupts the wave when ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(c *handleConfig) { c.ctx = ctx }
+}
+
+// WithTimeout interrupts the wave if it is still running d after it started,
+// after which TimedOut reports true. The timeout covers the whole wave, or all
+// waves of a Continuous wave, rather than single items.
+func WithTimeout(d time.Duration) Option {
+	return func(c *handleConfig) { c.timeout = d }
+}
+
+// WithRateLimit limits how many items per second are passed to the callback,
+// across all workers. Retries count as separate items.
+func WithRateLimit(rps float64) Option {
+	return func(c *handleConfig) { c.rateLimit = rps }
+}
+
+// WithMaxIterations makes a Continuous wave finish after n waves.
+func WithMaxIterations(n int) Option {
+	return func(c *handleConfig) { c.maxIterations = n }
+}
+
+// WithTapTimeout sets how long a tap may take for an item before it is
+// abandoned, like SetTapTimeout.
+func WithTapTimeout(d time.Duration) Option {
+	return func(c *handleConfig) { c.tapTimeout = d }
+}
+
+// WithRetry retries items whose callback chain fails, like RetryMiddleware,
+// making up to max attempts in total. The retries wrap any middleware added
+// later with Use.
+func WithRetry(max int, backoff time.Duration) Option {
+	return func(c *handleConfig) { c.retries, c.retryBackoff = max, backoff }
+}
+
+func newHandleConfig(opts []Option) *handleConfig {
+	c := &handleConfig{concurrency: 1, tapTimeout: defaultTapTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// apply configures h, which must not have started yet.
+func (c *handleConfig) apply(h *Handle) {
+	if c.retries > 0 {
+		h.Use(RetryMiddleware(c.retries, c.retryBackoff))
+	}
+	if c.rateLimit > 0 {
+		h.Use(rateLimit(c.rateLimit))
+	}
+	h.maxWaves = c.maxIterations
+	h.debugLog = c.debug
+	h.recovery = c.recovery
+	h.circuit = c.circuit
+	h.lock = c.lock
+	h.SetTapTimeout(c.tapTimeout)
+	h.setWatch(c.ctx, c.timeout)
+}
+
+// OnceWithOptions is like Once, but configured with options. Once(n, vals,
+// callback) is the same as OnceWithOptions(vals, callback,
+// WithConcurrency(n)).
+func OnceWithOptions(vals []string, callback func(string), opts ...Option) *Handle {
+	c := newHandleConfig(opts)
+	h := once(c.concurrency, nil, noError(callback))
+	h.setVals(vals)
+	c.apply(h)
+	return h
+}
+
+// ContinuousWithOptions is like Continuous, but configured with options.
+func ContinuousWithOptions(vals []string, callback func(string), opts ...Option) *Handle {
+	c := newHandleConfig(opts)
+	h := continuous(c.concurrency, nil, noError(callback))
+	h.setVals(vals)
+	c.apply(h)
+	return h
+}
+
+// setWatch interrupts the wave when ctx is done or timeout has passed since
+// Start, if either is set, and keeps them so that Recover can do the same.
+func (h *Handle) setWatch(ctx context.Context, timeout time.Duration) {
+	if ctx == nil && timeout <= 0 {
+		return
+	}
+	h.watchCtx, h.watchTimeout = ctx, timeout
+	go h.watch(ctx, timeout)
+}
+
+// watch interrupts h when ctx is done or timeout has passed since h started.
+func (h *Handle) watch(ctx context.Context, timeout time.Duration) {
+	select {
+	case <-h.startChan:
+	case <-h.stopChan:
+		return
+	}
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-done:
+		h.interrupt.Do(func() {
+			h.debug("close", "interruptChan", "reason", "context done")
+			close(h.interruptChan)
+		})
+	case <-expired:
+		h.interrupt.Do(func() {
+			h.timedOut.Store(true)
+			h.debug("close", "interruptChan", "reason", "timeout")
+			close(h.interruptChan)
+		})
+	case <-h.stopChan:
+	}
+}
+
+// TimedOut reports whether the wave was interrupted by WithTimeout.
+func (h *Handle) TimedOut() bool {
+	return h.timedOut.Load()
+}
+
+// rateLimit spaces out calls to the rest of the chain so that at most rps
+// start per second.
+func rateLimit(rps float64) CallbackMiddleware {
+	interval := time.Duration(float64(time.Second) / rps)
+	var slot time.Time   // When the next call may start
+	lock := sync.Mutex{} // Guards slot
+	return func(next func(string) error) func(string) error {
+		return func(val string) error {
+			lock.Lock()
+			now := time.Now()
+			if slot.Before(now) {
+				slot = now
+			}
+			wait := slot.Sub(now)
+			slot = slot.Add(interval)
+			lock.Unlock()
+			time.Sleep(wait)
+			return next(val)
+		}
+	}
+}
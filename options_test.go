@@ -0,0 +1,107 @@
+package wave
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOnceWithOptions(t *testing.T) {
+	var running, peak, calls atomic.Int64
+	w := OnceWithOptions(FakeEndpoints(), func(string) {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+	}, WithConcurrency(3), WithRetry(2, 0), WithRateLimit(1000))
+	var failed atomic.Bool
+	w.Use(func(next func(string) error) func(string) error {
+		return func(val string) error {
+			calls.Add(1)
+			if val == ":3000" && !failed.Swap(true) {
+				return errFault
+			}
+			return next(val)
+		}
+	})
+	w.Finish()
+
+	if n := peak.Load(); n < 2 || n > 3 {
+		t.Error("Expected up to 3 items at once, got", n)
+	}
+	if n := calls.Load(); n != numPorts+1 {
+		t.Error("Expected one retry, got", n-numPorts)
+	}
+	if errs := w.Errors(); errs != nil {
+		t.Error("Expected the retry to succeed, got", errs)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	w := OnceWithOptions(FakeEndpoints(), func(string) {}, WithConcurrency(numPorts), WithRateLimit(200))
+	began := time.Now()
+	w.Finish()
+	// 10 items at 200 per second take at least 9 intervals of 5ms.
+	if d := time.Since(began); d < 45*time.Millisecond {
+		t.Error("Expected the rate limit to slow the wave down, took", d)
+	}
+}
+
+func TestWithMaxIterations(t *testing.T) {
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithConcurrency(2), WithMaxIterations(3))
+	w.Start()
+	w.Wait()
+	if n := w.waves.Load(); n != 3 {
+		t.Error("Expected 3 waves, got", n)
+	}
+	if n := w.processed.Load(); n != 3*numPorts {
+		t.Error("Expected", 3*numPorts, "items, got", n)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) { time.Sleep(time.Millisecond) }, WithContext(ctx))
+	w.Start()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	if !w.waitTimeout(time.Second) {
+		t.Fatal("Expected cancelling the context to stop the wave")
+	}
+	if s := w.Snapshot().State; s != StateInterrupted {
+		t.Error("Expected the wave to be interrupted, got", s)
+	}
+}
+
+func TestWithTimeoutOption(t *testing.T) {
+	w := OnceWithOptions(FakeEndpoints(), func(string) { time.Sleep(20 * time.Millisecond) }, WithTimeout(10*time.Millisecond))
+	began := time.Now()
+	w.Start()
+	w.Wait()
+	if d := time.Since(began); d > 100*time.Millisecond {
+		t.Error("Expected the timeout to stop the wave promptly, took", d)
+	}
+	if n := w.CompletedCount(); n >= numPorts {
+		t.Error("Expected the wave to be cut short, got", n, "items")
+	}
+	if !w.TimedOut() {
+		t.Error("Expected TimedOut to report the timeout")
+	}
+}
+
+func TestTimedOut(t *testing.T) {
+	finished := OnceWithOptions(FakeEndpoints(), func(string) {}, WithTimeout(time.Second))
+	finished.Finish()
+	if finished.TimedOut() {
+		t.Error("Expected a wave that finished in time not to time out")
+	}
+
+	interrupted := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithTimeout(time.Second))
+	interrupted.Start()
+	interrupted.Interrupt()
+	if interrupted.TimedOut() {
+		t.Error("Expected an interrupted wave not to time out")
+	}
+}
@@ -0,0 +1,49 @@
+package wave
+
+import "sync"
+
+// PipeTo prepares a wave that runs once after h stops, over the items of h
+// passed through transform. Items for which transform returns false are left
+// out. If h is a Continuous wave, only the items of its last wave are kept.
+// Starting either wave starts both, and PipeTo can be chained to build a
+// longer pipeline:
+//
+//	h.PipeTo(5, needsRepair, repair).PipeTo(5, needsReboot, reboot).Finish()
+func (h *Handle) PipeTo(concurrency int, transform func(string) (string, bool), callback func(string)) *Handle {
+	var items []string
+	var wave int64       // Wave of h that items came from
+	lock := sync.Mutex{} // Guards items and wave
+	h.funcsLock.Lock()
+	h.pipeFuncs = append(h.pipeFuncs, func(val string) {
+		if item, ok := transform(val); ok {
+			lock.Lock()
+			if n := h.waves.Load(); n != wave {
+				items, wave = nil, n // Drop the items of earlier waves
+			}
+			items = append(items, item)
+			lock.Unlock()
+		}
+	})
+	h.funcsLock.Unlock()
+
+	next := newHandle(concurrency, func() Source {
+		lock.Lock()
+		defer lock.Unlock()
+		if wave != h.waves.Load() {
+			return SliceSource(nil) // The last wave of h produced no items
+		}
+		return SliceSource(items)
+	}, noError(callback))
+	next.prev = h
+	go next.runOnce()
+	go func() {
+		// h can only stop after it started, so its stopChan is no reason to
+		// give up: next must still run over the items of h.
+		select {
+		case <-h.startChan:
+			next.Start()
+		case <-next.stopChan:
+		}
+	}()
+	return next
+}
@@ -0,0 +1,109 @@
+package wave
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipeTo(t *testing.T) {
+	var first, second, third []string
+	h := Once(3, []string{"web-1", "db-1", "web-2", "db-2"}, record(&first))
+	last := h.PipeTo(2, func(val string) (string, bool) {
+		return strings.ToUpper(val), strings.HasPrefix(val, "db")
+	}, record(&second)).PipeTo(2, func(val string) (string, bool) {
+		return val + "!", true
+	}, record(&third))
+
+	last.Finish() // Starts the whole pipeline
+
+	slices.Sort(second)
+	slices.Sort(third)
+	if len(first) != 4 {
+		t.Error("Expected 4 items in the first stage, got", first)
+	}
+	if want := []string{"DB-1", "DB-2"}; !slices.Equal(second, want) {
+		t.Error("Expected", want, "got", second)
+	}
+	if want := []string{"DB-1!", "DB-2!"}; !slices.Equal(third, want) {
+		t.Error("Expected", want, "got", third)
+	}
+}
+
+func TestPipeToStartsFromFirstStage(t *testing.T) {
+	var got []string
+	h := Once(1, FakeEndpoints(), func(string) {})
+	next := h.PipeTo(1, func(val string) (string, bool) { return val, true }, record(&got))
+	h.Start()
+	next.Wait()
+	if len(got) != numPorts {
+		t.Error("Expected", numPorts, "got", len(got))
+	}
+}
+
+func TestPipeToContinuousKeepsLastWave(t *testing.T) {
+	var got []string
+	h := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithMaxIterations(3))
+	next := h.PipeTo(1, func(val string) (string, bool) { return val, true }, record(&got))
+	next.Finish()
+
+	slices.Sort(got)
+	if !slices.Equal(got, FakeEndpoints()) {
+		t.Error("Expected the items of the last wave only, got", len(got), "items")
+	}
+}
+
+func TestPipeToAfterFirstStageStopped(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		h := Once(2, FakeEndpoints(), func(string) {})
+		next := h.PipeTo(1, func(val string) (string, bool) { return val, true }, func(string) {})
+		h.Finish()
+		if !next.waitTimeout(5 * time.Second) {
+			t.Fatal("Expected the second stage to run after the first stage stopped")
+		}
+	}
+}
+
+func TestPipeToContinuousInterrupt(t *testing.T) {
+	h := Continuous(1, FakeEndpoints(), func(string) {})
+	next := h.PipeTo(1, func(val string) (string, bool) { return val, true }, func(string) {
+		t.Error("Expected the interrupted stage not to run")
+	})
+	next.Start()
+
+	done := make(chan struct{})
+	go func() {
+		next.Interrupt()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Interrupt hung waiting for the Continuous first stage")
+	}
+	if s := h.Snapshot().State; s != StateInterrupted {
+		t.Error("Expected the first stage to be interrupted too, got", s)
+	}
+}
+
+func TestPipeToContinuousFinish(t *testing.T) {
+	var got []string
+	h := Continuous(1, FakeEndpoints(), func(string) {})
+	next := h.PipeTo(1, func(val string) (string, bool) { return val, true }, record(&got))
+	next.Start()
+
+	done := make(chan struct{})
+	go func() {
+		next.Finish()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Finish hung waiting for the Continuous first stage")
+	}
+	if len(got) != numPorts {
+		t.Error("Expected the last wave of the first stage to be piped, got", len(got), "items")
+	}
+}
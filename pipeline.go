@@ -0,0 +1,86 @@
+package wave
+
+import (
+	"context"
+	"sync"
+)
+
+// PipelineStage is a step of a Pipeline. Fn is called once per item with the
+// stage's concurrency and returns the items for the next stage.
+type PipelineStage struct {
+	Concurrency int
+	Fn          func(string) ([]string, error)
+}
+
+// Pipeline runs a wave per stage, feeding the items returned by one stage to
+// the next, for processing chains like scan, enrich and store. Unlike PipeTo,
+// a stage can turn one item into any number of items.
+type Pipeline struct {
+	stages []PipelineStage
+	errors MultiError
+	lock   sync.Mutex // Guards errors
+}
+
+// PipelineOf returns a Pipeline that runs stages in order.
+func PipelineOf(stages []PipelineStage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Run passes vals through every stage and returns the items returned by the
+// last one. The items returned by a stage are deduplicated before they are
+// passed on, and items whose Fn fails contribute nothing. Cancelling ctx
+// interrupts the current stage and skips the rest.
+func (p *Pipeline) Run(ctx context.Context, vals []string) []string {
+	p.lock.Lock()
+	p.errors = nil
+	p.lock.Unlock()
+	for _, stage := range p.stages {
+		if ctx.Err() != nil {
+			return nil
+		}
+		vals = p.runStage(ctx, stage, vals)
+	}
+	return vals
+}
+
+func (p *Pipeline) runStage(ctx context.Context, stage PipelineStage, vals []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	lock := sync.Mutex{} // Guards out and seen
+	h := once(stage.Concurrency, SliceSource(vals), func(val string) error {
+		items, err := stage.Fn(val)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		for _, item := range items {
+			if !seen[item] {
+				seen[item] = true
+				out = append(out, item)
+			}
+		}
+		lock.Unlock()
+		return nil
+	})
+	h.Start()
+	select {
+	case <-h.stopChan:
+	case <-ctx.Done():
+		h.Interrupt()
+	}
+	p.lock.Lock()
+	p.errors = append(p.errors, h.Errors()...)
+	p.lock.Unlock()
+	return out
+}
+
+// Errors returns the errors of all stages of the current or most recent Run,
+// in stage order. It returns nil if there were none.
+func (p *Pipeline) Errors() MultiError {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.errors) == 0 {
+		return nil
+	}
+	return append(MultiError(nil), p.errors...)
+}
@@ -0,0 +1,60 @@
+package wave
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	p := PipelineOf([]PipelineStage{
+		{Concurrency: 2, Fn: func(val string) ([]string, error) {
+			// Every host has an a and a b record, and all share a gateway.
+			return []string{val + "/a", val + "/b", "gateway"}, nil
+		}},
+		{Concurrency: 4, Fn: func(val string) ([]string, error) {
+			if strings.HasSuffix(val, "/b") {
+				return nil, errFault
+			}
+			return []string{strings.ToUpper(val)}, nil
+		}},
+	})
+	out := p.Run(context.Background(), []string{"x", "y"})
+
+	slices.Sort(out)
+	if want := []string{"GATEWAY", "X/A", "Y/A"}; !slices.Equal(out, want) {
+		t.Error("Expected", want, "got", out)
+	}
+	errs := p.Errors()
+	if len(errs) != 2 {
+		t.Fatal("Expected 2 errors, got", errs)
+	}
+	for _, e := range errs {
+		if !errors.Is(e, errFault) || !strings.HasSuffix(e.Val, "/b") {
+			t.Error("Unexpected error", e)
+		}
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	p := PipelineOf([]PipelineStage{
+		{Concurrency: 1, Fn: func(val string) ([]string, error) {
+			cancel()
+			return []string{val}, nil
+		}},
+		{Concurrency: 1, Fn: func(val string) ([]string, error) {
+			calls++
+			return []string{val}, nil
+		}},
+	})
+	if out := p.Run(ctx, FakeEndpoints()); out != nil {
+		t.Error("Expected no output after cancelling, got", out)
+	}
+	if calls != 0 {
+		t.Error("Expected the second stage to be skipped, got", calls, "calls")
+	}
+}
@@ -0,0 +1,227 @@
+package wave
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrPoolClosed is returned when using a WorkerPool after Close.
+	ErrPoolClosed = errors.New("wave: worker pool is closed")
+	// ErrQueueFull is returned by Submit when the queue is at capacity.
+	ErrQueueFull = errors.New("wave: worker pool queue is full")
+	// ErrInvalidSize is returned by Resize for sizes below one.
+	ErrInvalidSize = errors.New("wave: worker pool size must be at least 1")
+)
+
+// WorkerPool is a fixed-size set of goroutines that pass queued items to a
+// callback. It is the primitive that waves are built on, and can be used on
+// its own to process items continuously without the wave abstraction.
+type WorkerPool struct {
+	callback func(string)
+
+	queue    []string
+	capacity int  // Maximum queue length, 0 for unbounded
+	size     int  // Requested number of workers
+	workers  int  // Number of live workers
+	active   int  // Number of workers inside the callback
+	closed   bool // Set by Close
+	panicked any  // First value a callback panicked with, until re-raised
+	lock     sync.Mutex
+	cond     *sync.Cond // Broadcast on any change to the fields above
+	done     sync.WaitGroup
+}
+
+// NewWorkerPool starts concurrency workers (at least one) that pass each
+// submitted item to callback. If callback panics, the worker recovers and
+// carries on, and the first panic is raised again by Close.
+func NewWorkerPool(concurrency int, callback func(string)) *WorkerPool {
+	p := &WorkerPool{callback: callback}
+	p.cond = sync.NewCond(&p.lock)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	p.Resize(concurrency)
+	return p
+}
+
+func (p *WorkerPool) work() {
+	defer p.done.Done()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for {
+		for len(p.queue) == 0 && !p.closed && p.workers <= p.size {
+			p.cond.Wait()
+		}
+		if p.workers > p.size || len(p.queue) == 0 {
+			// Surplus after a Resize, or closed and drained.
+			p.workers--
+			p.cond.Broadcast()
+			return
+		}
+		item := p.queue[0]
+		p.queue[0] = ""
+		p.queue = p.queue[1:]
+		p.active++
+		p.cond.Broadcast()
+		p.run(item)
+		p.active--
+		p.cond.Broadcast()
+	}
+}
+
+// run passes item to the callback without holding the lock, which is held
+// again when run returns, even if the callback panics.
+func (p *WorkerPool) run(item string) {
+	p.lock.Unlock()
+	defer func() {
+		r := recover()
+		p.lock.Lock()
+		if r != nil && p.panicked == nil {
+			p.panicked = r
+		}
+	}()
+	p.callback(item)
+}
+
+// repanic raises the first recovered callback panic, if any, in the calling
+// goroutine. The lock must be held.
+func (p *WorkerPool) repanic() {
+	if r := p.panicked; r != nil {
+		p.panicked = nil
+		panic(r)
+	}
+}
+
+// submit queues an item. If block is set it waits for room in the queue
+// instead of failing with ErrQueueFull.
+func (p *WorkerPool) submit(item string, block bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for !p.closed && p.full() {
+		if !block {
+			return ErrQueueFull
+		}
+		p.cond.Wait()
+	}
+	if p.closed {
+		return ErrPoolClosed
+	}
+	p.queue = append(p.queue, item)
+	p.cond.Broadcast()
+	return nil
+}
+
+func (p *WorkerPool) full() bool {
+	return p.capacity > 0 && len(p.queue) >= p.capacity
+}
+
+// wait blocks until the queue is empty and no callback is running, and then
+// raises a recovered callback panic like Close.
+func (p *WorkerPool) wait() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for len(p.queue) > 0 || p.active > 0 {
+		p.cond.Wait()
+	}
+	p.repanic()
+}
+
+// Submit queues an item for processing without blocking. It returns
+// ErrQueueFull if a queue capacity is set and has been reached.
+func (p *WorkerPool) Submit(item string) error {
+	return p.submit(item, false)
+}
+
+// SubmitBlocking queues an item for processing, waiting for room in the queue
+// if a queue capacity is set. It only fails if the pool is closed.
+func (p *WorkerPool) SubmitBlocking(item string) error {
+	return p.submit(item, true)
+}
+
+// SubmitBatch queues several items at once. The batch is queued atomically:
+// if a queue capacity is set and the batch does not fit in the remaining room,
+// none of the items are queued and ErrQueueFull is returned.
+func (p *WorkerPool) SubmitBatch(items []string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	if p.capacity > 0 && len(p.queue)+len(items) > p.capacity {
+		return ErrQueueFull
+	}
+	p.queue = append(p.queue, items...)
+	p.cond.Broadcast()
+	return nil
+}
+
+// SetQueueCapacity limits how many items may wait in the queue. Zero, the
+// default, means unbounded.
+func (p *WorkerPool) SetQueueCapacity(n int) {
+	p.lock.Lock()
+	p.capacity = n
+	p.cond.Broadcast()
+	p.lock.Unlock()
+}
+
+// Resize changes the number of workers. When shrinking, surplus workers exit
+// after finishing their current item.
+func (p *WorkerPool) Resize(n int) error {
+	if n < 1 {
+		return ErrInvalidSize
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	p.size = n
+	for p.workers < p.size {
+		p.workers++
+		p.done.Add(1)
+		go p.work()
+	}
+	p.cond.Broadcast()
+	return nil
+}
+
+// Close stops accepting items and blocks until all queued and in-flight items
+// have been processed and the workers have exited. If a callback panicked,
+// Close then panics with the same value.
+func (p *WorkerPool) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return ErrPoolClosed
+	}
+	p.closed = true
+	p.cond.Broadcast()
+	p.lock.Unlock()
+	p.done.Wait()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.repanic()
+	return nil
+}
+
+// QueueDepth returns the number of items waiting for a worker.
+func (p *WorkerPool) QueueDepth() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.queue)
+}
+
+// ActiveWorkers returns the number of live worker goroutines.
+func (p *WorkerPool) ActiveWorkers() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.workers
+}
+
+// inFlight returns the number of workers inside the callback.
+func (p *WorkerPool) inFlight() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.active
+}
@@ -0,0 +1,184 @@
+package wave
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolConcurrentSubmit(t *testing.T) {
+	var count atomic.Int32
+	p := NewWorkerPool(4, func(string) {
+		count.Add(1)
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := p.Submit(strconv.Itoa(j)); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := p.Close(); err != nil {
+		t.Error(err)
+	}
+	if n := count.Load(); n != 1000 {
+		t.Error("Expected 1000, got", n)
+	}
+	if err := p.Submit("late"); err != ErrPoolClosed {
+		t.Error("Expected ErrPoolClosed, got", err)
+	}
+}
+
+func TestWorkerPoolResize(t *testing.T) {
+	release := make(chan struct{})
+	p := NewWorkerPool(2, func(string) {
+		<-release
+	})
+	defer p.Close()
+
+	if err := p.Resize(0); err != ErrInvalidSize {
+		t.Error("Expected ErrInvalidSize, got", err)
+	}
+
+	p.Resize(5)
+	if n := p.ActiveWorkers(); n != 5 {
+		t.Error("Expected 5 workers, got", n)
+	}
+
+	p.Resize(1)
+	deadline := time.Now().Add(time.Second)
+	for p.ActiveWorkers() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := p.ActiveWorkers(); n != 1 {
+		t.Error("Expected 1 worker, got", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		p.Submit(strconv.Itoa(i))
+	}
+	deadline = time.Now().Add(time.Second)
+	for p.QueueDepth() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := p.QueueDepth(); n != 2 {
+		t.Error("Expected queue depth 2 with one busy worker, got", n)
+	}
+	close(release)
+}
+
+func TestWorkerPoolQueueCapacity(t *testing.T) {
+	release := make(chan struct{})
+	p := NewWorkerPool(1, func(string) {
+		<-release
+	})
+	p.SetQueueCapacity(1)
+
+	p.Submit("busy")
+	for p.QueueDepth() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Submit("queued"); err != nil {
+		t.Error(err)
+	}
+	if err := p.Submit("rejected"); err != ErrQueueFull {
+		t.Error("Expected ErrQueueFull, got", err)
+	}
+	close(release)
+	p.Close()
+}
+
+func TestWorkerPoolSubmitBatch(t *testing.T) {
+	var count atomic.Int32
+	p := NewWorkerPool(2, func(string) {
+		count.Add(1)
+	})
+	p.SetQueueCapacity(5)
+
+	if err := p.SubmitBatch(make([]string, 6)); err != ErrQueueFull {
+		t.Error("Expected ErrQueueFull, got", err)
+	}
+	if err := p.SubmitBatch(make([]string, 5)); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := p.SubmitBlocking(strconv.Itoa(i)); err != nil {
+			t.Error(err)
+		}
+	}
+	p.Close()
+
+	if n := count.Load(); n != 25 {
+		t.Error("Expected 25, got", n)
+	}
+	if err := p.SubmitBlocking("late"); err != ErrPoolClosed {
+		t.Error("Expected ErrPoolClosed, got", err)
+	}
+}
+
+func benchmarkItems() []string {
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+	return items
+}
+
+func BenchmarkWorkerPoolSubmit(b *testing.B) {
+	items := benchmarkItems()
+	p := NewWorkerPool(4, func(string) {})
+	defer p.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, item := range items {
+			p.Submit(item)
+		}
+		p.wait()
+	}
+}
+
+func BenchmarkWorkerPoolSubmitBatch(b *testing.B) {
+	items := benchmarkItems()
+	p := NewWorkerPool(4, func(string) {})
+	defer p.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.SubmitBatch(items)
+		p.wait()
+	}
+}
+
+func TestWorkerPoolCallbackPanic(t *testing.T) {
+	var count atomic.Int32
+	p := NewWorkerPool(2, func(item string) {
+		if item == "bad" {
+			panic("boom")
+		}
+		count.Add(1)
+	})
+	p.Submit("bad")
+	for i := 0; i < 10; i++ {
+		p.Submit(strconv.Itoa(i))
+	}
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Error("Expected the callback panic from Close, got", r)
+		}
+		if n := count.Load(); n != 10 {
+			t.Error("Expected the other items to be processed, got", n)
+		}
+	}()
+	p.Close()
+	t.Error("Expected Close to panic")
+}
@@ -0,0 +1,103 @@
+package wave
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+)
+
+// ErrNotPriorityWave is returned by SubmitWithPriority for waves that were not
+// created by OnceWithPriority, or whose items have all been handed out.
+var ErrNotPriorityWave = errors.New("wave: not a running priority wave")
+
+// PriorityItem is an item of a wave created by OnceWithPriority.
+type PriorityItem struct {
+	Val      string
+	Priority int
+}
+
+// OnceWithPriority is like Once, but hands out items with a higher Priority
+// first, so that critical servers are checked before the rest. Items with the
+// same priority are processed in the order given. More items can be added
+// while the wave runs with SubmitWithPriority.
+func OnceWithPriority(concurrency int, items []PriorityItem, callback func(string)) *Handle {
+	src := &prioritySource{}
+	for _, item := range items {
+		src.push(item)
+	}
+	h := once(concurrency, src, noError(callback))
+	h.priority = src
+	h.recoverable = false // The items added by SubmitWithPriority are gone
+	return h
+}
+
+// SubmitWithPriority adds an item to a wave created by OnceWithPriority. It is
+// handed out before any waiting item of lower priority, but after items that
+// are already queued for a worker. Once the wave has handed out its last item
+// it ends, and SubmitWithPriority returns ErrNotPriorityWave.
+func (h *Handle) SubmitWithPriority(val string, priority int) error {
+	if h.priority == nil || !h.priority.push(PriorityItem{Val: val, Priority: priority}) {
+		return ErrNotPriorityWave
+	}
+	return nil
+}
+
+// prioritySource is a Source that returns the item with the highest priority
+// first.
+type prioritySource struct {
+	items   priorityHeap
+	seq     int  // Order of the next item, to keep equal priorities FIFO
+	drained bool // Set once Next has reported the end of the items
+	lock    sync.Mutex
+}
+
+// push adds an item, unless the source has been drained.
+func (s *prioritySource) push(item PriorityItem) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.drained {
+		return false
+	}
+	heap.Push(&s.items, queuedItem{PriorityItem: item, seq: s.seq})
+	s.seq++
+	return true
+}
+
+func (s *prioritySource) Next() (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.items) == 0 {
+		s.drained = true
+		return "", false
+	}
+	return heap.Pop(&s.items).(queuedItem).Val, true
+}
+
+type queuedItem struct {
+	PriorityItem
+	seq int
+}
+
+// priorityHeap is a heap.Interface with the highest priority, then the
+// earliest item first.
+type priorityHeap []queuedItem
+
+func (q priorityHeap) Len() int { return len(q) }
+func (q priorityHeap) Less(i, j int) bool {
+	if q[i].Priority != q[j].Priority {
+		return q[i].Priority > q[j].Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q priorityHeap) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *priorityHeap) Push(x any) {
+	*q = append(*q, x.(queuedItem))
+}
+
+func (q *priorityHeap) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,55 @@
+package wave
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestOnceWithPriority(t *testing.T) {
+	var order []string
+	w := OnceWithPriority(1, []PriorityItem{
+		{"low1", 0}, {"high1", 10}, {"mid", 5}, {"low2", 0}, {"high2", 10},
+	}, record(&order))
+	w.Finish()
+
+	if want := []string{"high1", "high2", "mid", "low1", "low2"}; !slices.Equal(order, want) {
+		t.Error("Expected", want, "got", order)
+	}
+}
+
+func TestSubmitWithPriority(t *testing.T) {
+	var order []string
+	var w *Handle
+	rec := record(&order)
+	items := make([]PriorityItem, 6)
+	for i := range items {
+		items[i] = PriorityItem{Val: "low", Priority: 0}
+	}
+	w = OnceWithPriority(1, items, func(val string) {
+		if len(order) == 0 {
+			if err := w.SubmitWithPriority("urgent", 10); err != nil {
+				t.Error(err)
+			}
+		}
+		rec(val)
+	})
+	w.Finish()
+
+	// At most one low priority item is queued for the worker, and one more
+	// waiting for room in the queue, ahead of it.
+	if i := slices.Index(order, "urgent"); i < 1 || i > 3 {
+		t.Error("Expected the urgent item right after the first, got", order)
+	}
+	if len(order) != len(items)+1 {
+		t.Error("Expected", len(items)+1, "items, got", order)
+	}
+	if err := w.SubmitWithPriority("late", 10); !errors.Is(err, ErrNotPriorityWave) {
+		t.Error("Expected ErrNotPriorityWave after the wave, got", err)
+	}
+	plain := Once(1, nil, func(string) {})
+	if err := plain.SubmitWithPriority("x", 1); !errors.Is(err, ErrNotPriorityWave) {
+		t.Error("Expected ErrNotPriorityWave for a plain wave, got", err)
+	}
+	plain.Finish()
+}
@@ -0,0 +1,96 @@
+package wave
+
+import "errors"
+
+var (
+	// ErrNotStopped is returned by Recover for a handle that is still running.
+	ErrNotStopped = errors.New("wave: handle has not stopped")
+	// ErrNotRecoverable is returned by Recover and ReplayErrors for handles
+	// they cannot recreate.
+	ErrNotRecoverable = errors.New("wave: handle cannot be recovered")
+)
+
+// Recover returns a new, unstarted Handle with the same configuration as h,
+// which must have stopped, so that an interrupted or finished wave can be run
+// again. The callback, concurrency, registered functions, middleware and
+// settings are carried over; stats, errors and event channels are not. A
+// source that implements Resetter is reset before the first wave of a
+// recovered Once wave. A context or timeout set by WithContext or WithTimeout
+// applies to the recovered wave too, with the timeout measured from its Start,
+// so a recovered wave whose context is done is interrupted as soon as it
+// starts. Pipeline stages, scheduled waves, interleaved waves and handles
+// returned by ContinuousOnSignal, OnceWithPriority, WaitAny, WaitAll or
+// RestoreHandle cannot be recovered.
+func (h *Handle) Recover() (*Handle, error) {
+	if !h.stopped() {
+		return nil, ErrNotStopped
+	}
+	if !h.recoverable || h.prev != nil {
+		return nil, ErrNotRecoverable
+	}
+
+	newSource := h.newSource
+	if !h.continuous {
+		newSource = func() Source {
+			src := h.newSource()
+			if r, ok := src.(Resetter); ok {
+				r.Reset()
+			}
+			return src
+		}
+	}
+	r := newHandle(h.concurrency, newSource, h.callback)
+	if vals := h.vals.Load(); vals != nil {
+		r.setVals(*vals)
+	}
+	r.continuous, r.recoverable, r.name, r.maxWaves = h.continuous, true, h.waveName(), h.maxWaves
+
+	h.funcsLock.RLock()
+	r.stopFuncs = append(r.stopFuncs, h.stopFuncs...)
+	r.eachFuncs = append(r.eachFuncs, h.eachFuncs...)
+	r.tapFuncs = append(r.tapFuncs, h.tapFuncs...)
+	r.tapTimeout = h.tapTimeout
+	middleware := append([]CallbackMiddleware(nil), h.middleware...)
+	h.funcsLock.RUnlock()
+	r.Use(middleware...)
+
+	h.stateLock.RLock()
+	r.shuffle, r.shuffleSeed, r.dedup = h.shuffle, h.shuffleSeed, h.dedup
+	r.filter, r.transform, r.dlq, r.scheduler = h.filter, h.transform, h.dlq, h.scheduler
+	r.idempotency = h.idempotency
+	h.stateLock.RUnlock()
+
+	h.checkpoint.lock.Lock()
+	r.checkpoint.w, r.checkpoint.interval = h.checkpoint.w, h.checkpoint.interval
+	h.checkpoint.lock.Unlock()
+	h.tracker.lock.Lock()
+	r.tracker.topN = h.tracker.topN
+	h.tracker.lock.Unlock()
+	h.adaptive.lock.Lock()
+	if a := &h.adaptive; a.enabled {
+		r.adaptive.enabled, r.adaptive.min, r.adaptive.max = true, a.min, a.max
+		r.adaptive.target, r.adaptive.current = a.target, a.current
+	}
+	h.adaptive.lock.Unlock()
+	r.limit.Store(h.limit.Load())
+	if h.recovery != nil {
+		r.recovery = &panicRecovery{maxRestarts: h.recovery.maxRestarts, onPanic: h.recovery.onPanic}
+	}
+	if h.circuit != nil {
+		r.circuit = &waveCircuitBreaker{threshold: h.circuit.threshold, halfOpenAfter: h.circuit.halfOpenAfter}
+	}
+	r.lock, r.lockRetry = h.lock, h.lockRetry
+	r.debugLog = h.debugLog
+	r.dryRun.Store(h.dryRun.Load())
+	r.setWatch(h.watchCtx, h.watchTimeout)
+	h.seenLock.Lock()
+	r.SetIterationDeduplication(h.seen != nil)
+	h.seenLock.Unlock()
+
+	if r.continuous {
+		go r.runContinuous()
+	} else {
+		go r.runOnce()
+	}
+	return r, nil
+}
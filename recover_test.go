@@ -0,0 +1,156 @@
+package wave
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRecover(t *testing.T) {
+	var calls, each atomic.Int64
+	var w *Handle
+	w = Once(1, FakeEndpoints(), func(string) {
+		if calls.Add(1) == 3 {
+			go w.Interrupt()
+			<-w.interruptChan
+		}
+	})
+	w.AfterEach(func() { each.Add(1) })
+	w.SetDeduplication(true)
+	w.Start()
+	w.Wait()
+
+	r, err := w.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == w {
+		t.Fatal("Expected a new handle")
+	}
+	if s := r.Snapshot().State; s != StateIdle {
+		t.Error("Expected the recovered handle to be idle, got", s)
+	}
+	calls.Store(100) // Don't interrupt again
+	r.Finish()
+
+	if n := r.CompletedCount(); n != numPorts {
+		t.Error("Expected the recovered wave to process", numPorts, "items, got", n)
+	}
+	if n := each.Load(); n != 2 {
+		t.Error("Expected AfterEach to carry over, got", n, "calls")
+	}
+	if !r.dedup || r.concurrency != 1 {
+		t.Error("Expected settings to carry over")
+	}
+}
+
+func TestRecoverContinuous(t *testing.T) {
+	var w *Handle
+	w = Continuous(2, FakeEndpoints(), func(string) {})
+	w.AfterEach(func() { go w.Interrupt() })
+	w.Start()
+	w.Wait()
+
+	r, err := w.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AfterEach(func() { go r.Finish() })
+	r.Start()
+	r.Wait()
+	if n := r.waves.Load(); n < 1 {
+		t.Error("Expected the recovered wave to run, got", n, "waves")
+	}
+}
+
+func TestRecoverErrors(t *testing.T) {
+	w := Once(1, FakeEndpoints(), func(string) {})
+	if _, err := w.Recover(); !errors.Is(err, ErrNotStopped) {
+		t.Error("Expected ErrNotStopped, got", err)
+	}
+	next := w.PipeTo(1, func(val string) (string, bool) { return val, true }, func(string) {})
+	w.Finish()
+	next.Wait()
+	if _, err := next.Recover(); !errors.Is(err, ErrNotRecoverable) {
+		t.Error("Expected ErrNotRecoverable for a pipeline stage, got", err)
+	}
+	all := WaitAll()
+	all.Wait()
+	if _, err := all.Recover(); !errors.Is(err, ErrNotRecoverable) {
+		t.Error("Expected ErrNotRecoverable for WaitAll, got", err)
+	}
+
+	for name, h := range map[string]*Handle{
+		"ContinuousOnSignal": ContinuousOnSignal(1, FakeEndpoints(), make(chan struct{}), func(string) error { return nil }),
+		"OnceWithPriority":   OnceWithPriority(1, []PriorityItem{{Val: "a"}}, func(string) {}),
+		"Interleave":         Once(1, nil, func(string) {}).Interleave(Once(1, nil, func(string) {})),
+	} {
+		h.Start()
+		h.Interrupt()
+		if _, err := h.Recover(); !errors.Is(err, ErrNotRecoverable) {
+			t.Error("Expected ErrNotRecoverable for", name, "got", err)
+		}
+	}
+}
+
+func TestRecoverContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) {}, WithContext(ctx))
+	w.Start()
+	cancel()
+	w.Wait()
+
+	r, err := w.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Start()
+	if !r.waitTimeout(5 * time.Second) {
+		r.Interrupt()
+		t.Fatal("Expected the recovered wave to stop on the cancelled context")
+	}
+	if s := r.Snapshot().State; s != StateInterrupted {
+		t.Error("Expected the recovered wave to be interrupted, got", s)
+	}
+}
+
+func TestRecoverTimeout(t *testing.T) {
+	w := ContinuousWithOptions(FakeEndpoints(), func(string) { time.Sleep(time.Millisecond) },
+		WithTimeout(20*time.Millisecond))
+	w.Start()
+	w.Wait()
+
+	r, err := w.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Start()
+	if !r.waitTimeout(5 * time.Second) {
+		r.Interrupt()
+		t.Fatal("Expected the recovered wave to time out")
+	}
+	if !r.TimedOut() {
+		t.Error("Expected TimedOut to report the timeout")
+	}
+}
+
+func TestRecoverDryRun(t *testing.T) {
+	var calls atomic.Int64
+	w := Once(1, FakeEndpoints(), func(string) { calls.Add(1) })
+	w.SetDryRun(true)
+	w.Finish()
+
+	r, err := w.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Finish()
+	if n := calls.Load(); n != 0 {
+		t.Error("Expected the recovered wave to stay a dry run, got", n, "calls")
+	}
+	if n := r.DryRunCount(); n != numPorts {
+		t.Error("Expected", numPorts, "items counted, got", n)
+	}
+}
@@ -0,0 +1,61 @@
+package wave
+
+import "fmt"
+
+// panicRecovery restarts waves that panic, see WithPanicRecovery.
+type panicRecovery struct {
+	maxRestarts int
+	onPanic     func(r any)
+	restarts    int // Used by the goroutine running the waves
+}
+
+// WithPanicRecovery recovers panics instead of crashing the program, and
+// calls onPanic with each recovered value. A panic in a callback or its
+// middleware fails only that item, which is recorded as an ItemError, and the
+// wave carries on; onPanic may then be called from several workers at once. A
+// panic in the goroutine that runs the waves, for example in a Source or a
+// filter, restarts the wave, up to maxRestarts times in total. After that the
+// wave stops.
+func WithPanicRecovery(maxRestarts int, onPanic func(r any)) Option {
+	return func(c *handleConfig) {
+		c.recovery = &panicRecovery{maxRestarts: maxRestarts, onPanic: onPanic}
+	}
+}
+
+// runWave runs a wave over a new source. With WithPanicRecovery, a panic is
+// recovered and runWave reports whether the wave may be restarted.
+func (h *Handle) runWave(pool dispatcher) (panicked, restart bool) {
+	if h.recovery == nil {
+		doTheWave(h.newSource(), pool, h)
+		return false, false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			pool.wait()
+			if h.recovery.onPanic != nil {
+				h.recovery.onPanic(r)
+			}
+			panicked = true
+			restart = h.recovery.restarts < h.recovery.maxRestarts
+			h.recovery.restarts++
+		}
+	}()
+	doTheWave(h.newSource(), pool, h)
+	return false, false
+}
+
+// callItem is like call, but with WithPanicRecovery a panic in the callback
+// chain is recovered and returned as the error of the item.
+func (h *Handle) callItem(chain func(string) error, transform func(string) string, val string) (err error) {
+	if h.recovery != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				if h.recovery.onPanic != nil {
+					h.recovery.onPanic(r)
+				}
+				err = fmt.Errorf("wave: callback for %q panicked: %v", val, r)
+			}
+		}()
+	}
+	return call(chain, transform, val)
+}
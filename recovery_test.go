@@ -0,0 +1,69 @@
+package wave
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithPanicRecovery(t *testing.T) {
+	var panics []any
+	var w *Handle
+	w = ContinuousWithOptions(FakeEndpoints(), func(string) {},
+		WithConcurrency(2),
+		WithMaxIterations(5),
+		WithPanicRecovery(3, func(r any) { panics = append(panics, r) }))
+	w.SetFilter(func(val string) bool {
+		if val == ":3005" && (w.waves.Load() == 2 || w.waves.Load() == 4) {
+			panic("bad filter")
+		}
+		return true
+	})
+	w.Start()
+	w.Wait()
+
+	if len(panics) != 2 || panics[0] != "bad filter" {
+		t.Error("Expected 2 recovered panics, got", panics)
+	}
+	if n := w.waves.Load(); n != 5 {
+		t.Error("Expected the wave to keep going for 5 waves, got", n)
+	}
+}
+
+func TestWithPanicRecoveryExhausted(t *testing.T) {
+	var panics atomic.Int64
+	w := OnceWithOptions(FakeEndpoints(), func(string) {},
+		WithPanicRecovery(2, func(any) { panics.Add(1) }))
+	w.SetFilter(func(string) bool { panic("always") })
+	w.Start()
+	w.Wait()
+
+	if n := panics.Load(); n != 3 {
+		t.Error("Expected the first run and 2 restarts to panic, got", n)
+	}
+	if s := w.Snapshot().State; s != StateStopped {
+		t.Error("Expected the wave to stop, got", s)
+	}
+}
+
+func TestWithPanicRecoveryCallback(t *testing.T) {
+	var panics atomic.Int64
+	w := OnceWithOptions(FakeEndpoints(), func(val string) {
+		if val == ":3002" || val == ":3007" {
+			panic("bad item")
+		}
+	}, WithConcurrency(3), WithPanicRecovery(0, func(any) { panics.Add(1) }))
+	w.Finish()
+
+	if n := panics.Load(); n != 2 {
+		t.Error("Expected 2 recovered panics, got", n)
+	}
+	if n := w.CompletedCount(); n != numPorts {
+		t.Error("Expected the wave to process every item, got", n)
+	}
+	if errs := w.Errors(); len(errs) != 2 {
+		t.Error("Expected the panicking items as errors, got", errs)
+	}
+	if s := w.Snapshot().State; s != StateStopped {
+		t.Error("Expected the wave to stop normally, got", s)
+	}
+}
@@ -0,0 +1,164 @@
+package wave
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"sync"
+)
+
+// ErrDuplicateName is returned by Registry.Register, OnceNamed and
+// ContinuousNamed when the name is already registered.
+var ErrDuplicateName = errors.New("wave: name already registered")
+
+// DefaultRegistry is the Registry used by OnceNamed and ContinuousNamed.
+var DefaultRegistry = &Registry{}
+
+// Registry keeps track of waves by name so that a service running many waves
+// can list, query and stop them in one place. The zero value is an empty
+// registry ready to use.
+type Registry struct {
+	handles map[string]*Handle
+	lock    sync.RWMutex // Guards handles
+}
+
+// Register adds h to the registry under name, and names h after it, as
+// reported by its events, Snapshot, String and Metrics. It returns
+// ErrDuplicateName if name is already registered.
+func (r *Registry) Register(name string, h *Handle) error {
+	_, err := r.add(name, func() *Handle { return h })
+	return err
+}
+
+// add registers the Handle returned by newHandle under name, only calling it
+// if name is free.
+func (r *Registry) add(name string, newHandle func() *Handle) (*Handle, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.handles[name]; ok {
+		return nil, ErrDuplicateName
+	}
+	if r.handles == nil {
+		r.handles = map[string]*Handle{}
+	}
+	h := newHandle()
+	h.setName(name)
+	r.handles[name] = h
+	return h, nil
+}
+
+// Get returns the Handle registered under name.
+func (r *Registry) Get(name string) (*Handle, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	h, ok := r.handles[name]
+	return h, ok
+}
+
+// Unregister removes name from the registry. The wave itself is not stopped.
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	delete(r.handles, name)
+	r.lock.Unlock()
+}
+
+// List returns the registered names in sorted order.
+func (r *Registry) List() []string {
+	r.lock.RLock()
+	names := make([]string, 0, len(r.handles))
+	for name := range r.handles {
+		names = append(names, name)
+	}
+	r.lock.RUnlock()
+	slices.Sort(names)
+	return names
+}
+
+// StopAll interrupts every registered wave and blocks until they have
+// stopped. Waves that were not started yet are stopped without processing any
+// items. The waves stay registered.
+func (r *Registry) StopAll() {
+	r.lock.RLock()
+	handles := make([]*Handle, 0, len(r.handles))
+	for _, h := range r.handles {
+		handles = append(handles, h)
+	}
+	r.lock.RUnlock()
+
+	wg := sync.WaitGroup{}
+	for _, h := range handles {
+		wg.Add(1)
+		go func() {
+			h.interrupt.Do(func() {
+				h.debug("close", "interruptChan", "reason", "StopAll")
+				close(h.interruptChan)
+			})
+			h.Start() // An unstarted wave would never stop otherwise
+			h.Wait()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+// Snapshot returns a snapshot of every registered wave by name.
+func (r *Registry) Snapshot() map[string]HandleSnapshot {
+	r.lock.RLock()
+	handles := make(map[string]*Handle, len(r.handles))
+	for name, h := range r.handles {
+		handles[name] = h
+	}
+	r.lock.RUnlock()
+
+	snaps := make(map[string]HandleSnapshot, len(handles))
+	for name, h := range handles {
+		snaps[name] = h.Snapshot()
+	}
+	return snaps
+}
+
+// OnceNamed is like Once, but names the wave and registers it in
+// DefaultRegistry. The name is reported in the WaveName of its events. It
+// returns ErrDuplicateName without creating a wave if name is taken.
+func OnceNamed(name string, concurrency int, vals []string, callback func(string)) (*Handle, error) {
+	return DefaultRegistry.add(name, func() *Handle {
+		return Once(concurrency, vals, callback)
+	})
+}
+
+// ContinuousNamed is like Continuous, but names the wave and registers it in
+// DefaultRegistry. The name is reported in the WaveName of its events. It
+// returns ErrDuplicateName without creating a wave if name is taken.
+func ContinuousNamed(name string, concurrency int, vals []string, callback func(string)) (*Handle, error) {
+	return DefaultRegistry.add(name, func() *Handle {
+		return Continuous(concurrency, vals, callback)
+	})
+}
+
+// MarshalPrometheus writes the metrics of every registered wave to w in the
+// Prometheus text exposition format, like MetricsSnapshot.MarshalPrometheus,
+// with one HELP and TYPE comment per metric. Samples are labelled with the
+// name each wave is registered under.
+func (r *Registry) MarshalPrometheus(w io.Writer) error {
+	var snaps []MetricsSnapshot
+	for _, name := range r.List() {
+		if h, ok := r.Get(name); ok {
+			m := h.Metrics()
+			m.WaveName = name
+			snaps = append(snaps, m)
+		}
+	}
+	return writePrometheus(w, snaps)
+}
+
+func (h *Handle) setName(name string) {
+	h.stateLock.Lock()
+	h.name = name
+	h.stateLock.Unlock()
+}
+
+func (h *Handle) waveName() string {
+	h.stateLock.RLock()
+	defer h.stateLock.RUnlock()
+	return h.name
+}
@@ -0,0 +1,122 @@
+package wave
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistryStopAll(t *testing.T) {
+	r := &Registry{}
+	var names []string
+	for i := range 10 {
+		name := fmt.Sprint("wave-", i)
+		names = append(names, name)
+		w := Continuous(2, FakeEndpoints(), func(string) {})
+		if i%2 == 0 {
+			w.Start() // Leave the odd ones unstarted
+		}
+		if err := r.Register(name, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Register("wave-0", Once(1, nil, func(string) {})); !errors.Is(err, ErrDuplicateName) {
+		t.Error("Expected ErrDuplicateName, got", err)
+	}
+	if list := r.List(); !slices.Equal(list, names) {
+		t.Error("Expected", names, "got", list)
+	}
+
+	r.StopAll()
+	for name, snap := range r.Snapshot() {
+		if snap.State != StateInterrupted {
+			t.Error("Expected", name, "to be interrupted, got", snap.State)
+		}
+	}
+	if h, ok := r.Get("wave-1"); !ok || h.processed.Load() != 0 {
+		t.Error("Expected the unstarted wave-1 to stop without processing items")
+	}
+
+	r.Unregister("wave-0")
+	if _, ok := r.Get("wave-0"); ok {
+		t.Error("Expected wave-0 to be unregistered")
+	}
+	if n := len(r.List()); n != 9 {
+		t.Error("Expected 9 waves left, got", n)
+	}
+}
+
+func TestOnceNamed(t *testing.T) {
+	w, err := OnceNamed("test-once-named", 1, FakeEndpoints(), func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DefaultRegistry.Unregister("test-once-named")
+	events := w.Events(1)
+	w.Start()
+	if ev := <-events; ev.WaveName != "test-once-named" {
+		t.Error("Expected the events to carry the wave name, got", ev.WaveName)
+	}
+	w.Wait()
+
+	if _, err := ContinuousNamed("test-once-named", 1, FakeEndpoints(), func(string) {}); !errors.Is(err, ErrDuplicateName) {
+		t.Error("Expected ErrDuplicateName, got", err)
+	}
+	if h, ok := DefaultRegistry.Get("test-once-named"); !ok || h != w {
+		t.Error("Expected the registered handle to be the wave")
+	}
+}
+
+func TestRegistryMarshalPrometheus(t *testing.T) {
+	r := &Registry{}
+	a := Once(1, FakeEndpoints(), func(string) {})
+	b := Once(2, FakeEndpoints(), func(string) {})
+	r.Register("a", a)
+	r.Register("b", b)
+	a.Finish()
+	b.Finish()
+
+	buf := &bytes.Buffer{}
+	if err := r.MarshalPrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		`wave_items_processed_total{wave="a"} 10`,
+		`wave_concurrency{wave="b"} 2`,
+		`wave_info{wave="a",wave_id="` + a.ID() + `"} 1`,
+		`wave_info{wave="b",wave_id="` + b.ID() + `"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, out)
+		}
+	}
+	if n := strings.Count(out, "# TYPE wave_items_processed_total "); n != 1 {
+		t.Error("Expected one TYPE comment per metric, got", n)
+	}
+	if n := strings.Count(out, "# HELP wave_state "); n != 1 {
+		t.Error("Expected one HELP comment per metric, got", n)
+	}
+}
+
+func TestRegisterNamesHandle(t *testing.T) {
+	r := &Registry{}
+	w := Once(1, FakeEndpoints(), func(string) {})
+	if err := r.Register("pinger", w); err != nil {
+		t.Fatal(err)
+	}
+	w.Finish()
+
+	if name := w.Snapshot().Name; name != "pinger" {
+		t.Error("Expected the snapshot to carry the name, got", name)
+	}
+	if s := w.String(); !strings.HasPrefix(s, "Handle{name:pinger, state:stopped,") {
+		t.Error("Expected String to include the name, got", s)
+	}
+	if name := w.Metrics().WaveName; name != "pinger" {
+		t.Error("Expected the metrics to carry the name, got", name)
+	}
+}
@@ -0,0 +1,247 @@
+package wave
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheduler runs waves at times given by a schedule instead of back to back.
+// The zero value is ready to use.
+type Scheduler struct {
+	// Now returns the current time. It defaults to time.Now and can be
+	// replaced to run schedules against a fake clock.
+	Now func() time.Time
+
+	after   func(time.Duration) <-chan time.Time // Defaults to time.After
+	handles []*Handle
+	lock    sync.Mutex // Guards handles
+}
+
+// ScheduleEntry is a schedule waiting for a wave to run. Call Do to create
+// the wave.
+type ScheduleEntry struct {
+	s    *Scheduler
+	next func(after time.Time) time.Time // Zero when there are no more runs
+}
+
+func (s *Scheduler) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Scheduler) wait(d time.Duration) <-chan time.Time {
+	if s.after != nil {
+		return s.after(d)
+	}
+	return time.After(d)
+}
+
+// Every schedules a wave to run every d, starting d after Start.
+func (s *Scheduler) Every(d time.Duration) *ScheduleEntry {
+	return &ScheduleEntry{s: s, next: func(after time.Time) time.Time {
+		return after.Add(d)
+	}}
+}
+
+// At schedules a wave to run once at t. If t has passed by the time the
+// scheduler is started, the wave does not run.
+func (s *Scheduler) At(t time.Time) *ScheduleEntry {
+	return &ScheduleEntry{s: s, next: func(after time.Time) time.Time {
+		if t.After(after) {
+			return t
+		}
+		return time.Time{}
+	}}
+}
+
+// Cron schedules a wave to run whenever the time matches a standard five
+// field cron expression: minute, hour, day of month, month and day of week.
+// Each field is *, a number, a range like 1-5, or a comma-separated list of
+// these, optionally followed by a step like */15. Cron panics if spec is
+// invalid, like regexp.MustCompile.
+func (s *Scheduler) Cron(spec string) *ScheduleEntry {
+	c, err := parseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return &ScheduleEntry{s: s, next: c.next}
+}
+
+// Do prepares a wave over vals that runs according to the schedule once the
+// scheduler is started. Finish on the returned handle lets the current wave
+// complete and cancels the remaining runs.
+func (e *ScheduleEntry) Do(concurrency int, vals []string, callback func(string)) *Handle {
+	h := newHandle(concurrency, func() Source { return SliceSource(vals) }, noError(callback))
+	go e.run(h)
+	e.s.lock.Lock()
+	e.s.handles = append(e.s.handles, h)
+	e.s.lock.Unlock()
+	return h
+}
+
+func (e *ScheduleEntry) run(h *Handle) {
+	<-h.startChan
+	pool := h.newPool()
+loop:
+	for {
+		now := e.s.now()
+		next := e.next(now)
+		if next.IsZero() {
+			break
+		}
+		select {
+		case <-e.s.wait(next.Sub(now)):
+			doTheWave(h.newSource(), pool, h)
+		case <-h.interruptChan:
+			break loop
+		case <-h.finishChan:
+			break loop
+		}
+	}
+	pool.Close()
+	h.stop()
+}
+
+// Start starts every scheduled wave.
+func (s *Scheduler) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, h := range s.handles {
+		h.Start()
+	}
+}
+
+// Stop interrupts every scheduled wave and blocks until they have stopped.
+func (s *Scheduler) Stop() {
+	s.lock.Lock()
+	handles := append([]*Handle(nil), s.handles...)
+	s.lock.Unlock()
+	for _, h := range handles {
+		h.Interrupt()
+	}
+}
+
+// cron is a parsed cron expression. Each field has a bit set for every value
+// it matches.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseCron(spec string) (*cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("wave: cron spec %q must have 5 fields", spec)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("wave: cron spec %q: %s: %v", spec, cronFields[i].name, err)
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1 // 7 is Sunday, like 0
+	}
+	return &cron{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		anyDom: strings.HasPrefix(fields[2], "*"), // Like cron, */2 counts as * here
+		anyDow: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value in %q", part)
+				}
+			} else if rng != part {
+				hi = max // A step after a single value, like 5/15, runs to the end
+			}
+			if lo < min || hi > max || lo > hi {
+				return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		return dom || dow // Cron matches either when both are restricted
+	}
+}
+
+// next returns the first matching minute after t, or the zero time if there
+// is none within five years.
+func (c *cron) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
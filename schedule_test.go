@@ -0,0 +1,140 @@
+package wave
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock makes s run against a clock that jumps ahead whenever the
+// scheduler waits.
+func fakeClock(s *Scheduler, start time.Time) {
+	now := start
+	lock := sync.Mutex{}
+	s.Now = func() time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+		return now
+	}
+	s.after = func(d time.Duration) <-chan time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+		now = now.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- now
+		return ch
+	}
+}
+
+func TestSchedulerCron(t *testing.T) {
+	s := &Scheduler{}
+	fakeClock(s, time.Date(2026, 1, 1, 10, 2, 30, 0, time.UTC))
+
+	var fired []time.Time
+	h := s.Cron("*/5 * * * *").Do(2, FakeEndpoints(), func(string) {})
+	h.AfterEach(func() {
+		if fired = append(fired, s.Now()); len(fired) == 3 {
+			go h.Finish()
+		}
+	})
+	s.Start()
+	h.Wait()
+
+	for i, want := range []string{"10:05", "10:10", "10:15"} {
+		if got := fired[i].Format("15:04"); got != want {
+			t.Error("Expected run", i, "at", want, "got", got)
+		}
+	}
+}
+
+func TestSchedulerEveryAndAt(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s := &Scheduler{}
+	fakeClock(s, start)
+
+	var every []time.Time
+	e := s.Every(90*time.Second).Do(1, []string{"host"}, func(string) {})
+	e.AfterEach(func() {
+		if every = append(every, s.Now()); len(every) == 2 {
+			go e.Finish()
+		}
+	})
+	at := 0
+	a := s.At(start.Add(-time.Minute)).Do(1, []string{"host"}, func(string) { at++ })
+
+	s.Start()
+	e.Wait()
+	a.Wait() // Already passed, so never runs
+	s.Stop()
+
+	if every[0].Sub(start) != 90*time.Second {
+		t.Error("Expected first run after 90s, got", every[0].Sub(start))
+	}
+	if at != 0 {
+		t.Error("Expected past At not to run, got", at)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	saturday := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		spec, want string
+	}{
+		{"0 9 * * 1-5", "2026-10-19 09:00"},
+		{"30 */6 * * *", "2026-10-17 12:30"},
+		{"0 0 1 1 *", "2027-01-01 00:00"},
+		{"15,45 8 * * 7", "2026-10-18 08:15"},
+		{"0 0 13 * 5", "2026-10-23 00:00"}, // Friday or the 13th
+		{"5/15 * * * *", "2026-10-17 12:05"},
+		{"0 0 13 * */1", "2026-11-13 00:00"}, // Only the 13th, as */1 counts as *
+		{"0 0 */1 * 5", "2026-10-23 00:00"},  // Only Fridays
+	}
+	for _, c := range cases {
+		cr, err := parseCron(c.spec)
+		if err != nil {
+			t.Error(c.spec, err)
+			continue
+		}
+		if got := cr.next(saturday).Format("2006-01-02 15:04"); got != c.want {
+			t.Error(c.spec, "expected", c.want, "got", got)
+		}
+	}
+
+	steps := []struct {
+		field string
+		min   int
+		max   int
+		want  []int
+	}{
+		{"5/15", 0, 59, []int{5, 20, 35, 50}},
+		{"*/20", 0, 59, []int{0, 20, 40}},
+		{"10-30/10", 0, 59, []int{10, 20, 30}},
+		{"1-6/2", 1, 31, []int{1, 3, 5}},
+		{"20/2", 0, 23, []int{20, 22}},
+		{"*/5", 1, 12, []int{1, 6, 11}},
+		{"0/30,45", 0, 59, []int{0, 30, 45}},
+	}
+	for _, c := range steps {
+		bits, err := parseCronField(c.field, c.min, c.max)
+		if err != nil {
+			t.Error(c.field, err)
+			continue
+		}
+		var got []int
+		for v := c.min; v <= c.max; v++ {
+			if bits&(1<<uint(v)) != 0 {
+				got = append(got, v)
+			}
+		}
+		if !slices.Equal(got, c.want) {
+			t.Error(c.field, "expected", c.want, "got", got)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Error("Expected error for", spec)
+		}
+	}
+}
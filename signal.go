@@ -0,0 +1,65 @@
+package wave
+
+// defaultSignalBuffer is how many signals ContinuousOnSignal queues while a
+// wave runs, unless changed with SetSignalBuffer.
+const defaultSignalBuffer = 1
+
+// ContinuousOnSignal prepares a wave over vals that runs once for every
+// signal received on trigger, instead of back to back. A signal that arrives
+// while a wave runs is queued and starts the next wave once it completes.
+// Signals beyond the buffer set by SetSignalBuffer are dropped, so the sender
+// never blocks for long. The wave stops once trigger is closed and the queued
+// waves have run, or when it is interrupted or finished.
+func ContinuousOnSignal(concurrency int, vals []string, trigger <-chan struct{}, callback func(string) error) *Handle {
+	h := newHandle(concurrency, nil, callback)
+	h.setVals(vals)
+	h.continuous = true
+	h.signalBuffer.Store(defaultSignalBuffer)
+	go h.runOnSignal(trigger)
+	return h
+}
+
+// SetSignalBuffer sets how many signals a wave created by ContinuousOnSignal
+// queues while a wave runs. It must be called before Start.
+func (h *Handle) SetSignalBuffer(n int) {
+	h.signalBuffer.Store(int64(max(n, 0)))
+}
+
+func (h *Handle) runOnSignal(trigger <-chan struct{}) {
+	<-h.startChan
+	pool := h.newPool()
+	signals := make(chan struct{}, h.signalBuffer.Load())
+	go func() {
+		defer close(signals)
+		for {
+			select {
+			case _, ok := <-trigger:
+				if !ok {
+					return
+				}
+				select {
+				case signals <- struct{}{}:
+				default: // Enough waves are queued already
+				}
+			case <-h.stopChan:
+				return
+			}
+		}
+	}()
+loop:
+	for {
+		select {
+		case <-h.interruptChan:
+			break loop
+		case <-h.finishChan:
+			break loop
+		case _, ok := <-signals:
+			if !ok {
+				break loop
+			}
+			doTheWave(h.newSource(), pool, h)
+		}
+	}
+	pool.Close()
+	h.stop()
+}
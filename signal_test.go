@@ -0,0 +1,64 @@
+package wave
+
+import (
+	"testing"
+)
+
+func TestContinuousOnSignal(t *testing.T) {
+	trigger := make(chan struct{})
+	done := make(chan struct{})
+	w := ContinuousOnSignal(3, FakeEndpoints(), trigger, func(string) error { return nil })
+	w.AfterEach(func() { done <- struct{}{} })
+	w.Start()
+
+	for i := 0; i < 3; i++ {
+		trigger <- struct{}{}
+		<-done
+	}
+	close(trigger)
+	w.Wait()
+
+	if n := w.waves.Load(); n != 3 {
+		t.Error("Expected a wave per signal, got", n)
+	}
+	if n := w.processed.Load(); n != 3*numPorts {
+		t.Error("Expected", 3*numPorts, "items, got", n)
+	}
+}
+
+func TestContinuousOnSignalQueue(t *testing.T) {
+	trigger := make(chan struct{})
+	running := make(chan struct{}, 1)
+	release := make(chan struct{})
+	w := ContinuousOnSignal(1, FakeEndpoints(), trigger, func(string) error {
+		select {
+		case running <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	})
+	w.Start()
+
+	trigger <- struct{}{}
+	<-running
+	// One signal is queued while the wave runs, the other is dropped.
+	trigger <- struct{}{}
+	trigger <- struct{}{}
+	close(trigger)
+	close(release)
+	w.Wait()
+
+	if n := w.waves.Load(); n != 2 {
+		t.Error("Expected 2 waves, got", n)
+	}
+}
+
+func TestContinuousOnSignalFinish(t *testing.T) {
+	trigger := make(chan struct{})
+	w := ContinuousOnSignal(1, FakeEndpoints(), trigger, func(string) error { return nil })
+	w.Finish()
+	if n := w.waves.Load(); n != 0 {
+		t.Error("Expected no waves without a signal, got", n)
+	}
+}
@@ -0,0 +1,213 @@
+package wave
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// State is the lifecycle state of a wave.
+type State int
+
+// States of a wave.
+const (
+	StateIdle        State = iota // Not started yet
+	StateRunning                  // Started and not paused
+	StatePaused                   // Paused by PauseOn
+	StateStopped                  // Stopped after completing or being finished
+	StateInterrupted              // Stopped after being interrupted
+)
+
+var stateNames = []string{"idle", "running", "paused", "stopped", "interrupted"}
+
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return "unknown"
+	}
+	return stateNames[s]
+}
+
+// MarshalText encodes the state as its name.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state from its name.
+func (s *State) UnmarshalText(text []byte) error {
+	for i, name := range stateNames {
+		if string(text) == name {
+			*s = State(i)
+			return nil
+		}
+	}
+	return errors.New("wave: unknown state " + strconv.Quote(string(text)))
+}
+
+// HandleSnapshot captures the state of a Handle. It can be encoded as JSON and
+// later turned back into a stopped Handle with RestoreHandle.
+type HandleSnapshot struct {
+	State               State               `json:"state"`
+	Name                string              `json:"name,omitempty"` // Set by Registry.Register
+	WaveID              string              `json:"wave_id"`
+	Waves               int64               `json:"waves"`               // Waves started so far, so the current wave number
+	ProcessedInWave     int64               `json:"processed_in_wave"`   // Items processed by the current or last wave
+	Processed           int64               `json:"processed"`           // Items processed by all waves
+	ErrorCount          int64               `json:"error_count"`         // Items that failed in all waves
+	Errors              []SnapshotError     `json:"errors,omitempty"`    // Of the current or last wave
+	Concurrency         int                 `json:"concurrency"`         // Requested number of workers
+	QueueDepth          int                 `json:"queue_depth"`         // Items waiting for a worker
+	StartTime           time.Time           `json:"start_time"`          // Zero if not started
+	LastWaveCompletedAt time.Time           `json:"last_wave_completed"` // Zero if no wave has completed
+	Callbacks           map[string][]string `json:"callbacks,omitempty"` // Function names by registration method
+}
+
+// SnapshotError is an ItemError in a HandleSnapshot.
+type SnapshotError struct {
+	Val string `json:"val"`
+	Err string `json:"err"`
+}
+
+// Snapshot captures the current state of the wave. It is safe to call at any
+// time.
+func (h *Handle) Snapshot() HandleSnapshot {
+	h.stateLock.RLock()
+	snap := HandleSnapshot{
+		State:               h.state(),
+		Name:                h.name,
+		WaveID:              h.id,
+		Waves:               h.waves.Load(),
+		ProcessedInWave:     h.waveProcessed.Load(),
+		Processed:           h.processed.Load(),
+		ErrorCount:          h.errorCount.Load(),
+		Concurrency:         h.concurrency,
+		StartTime:           h.startTime,
+		LastWaveCompletedAt: h.lastWaveTime,
+		Callbacks:           map[string][]string{},
+	}
+	h.stateLock.RUnlock()
+	if pool := h.pool.Load(); pool != nil {
+		snap.QueueDepth = pool.QueueDepth()
+	}
+	for _, e := range h.Errors() {
+		snap.Errors = append(snap.Errors, SnapshotError{Val: e.Val, Err: e.Err.Error()})
+	}
+
+	h.funcsLock.RLock()
+	for _, f := range h.eachFuncs {
+		snap.Callbacks["AfterEach"] = append(snap.Callbacks["AfterEach"], funcName(f))
+	}
+	for _, f := range h.stopFuncs {
+		snap.Callbacks["OnStop"] = append(snap.Callbacks["OnStop"], funcName(f))
+	}
+	for _, f := range h.tapFuncs {
+		snap.Callbacks["Tap"] = append(snap.Callbacks["Tap"], funcName(f))
+	}
+	for _, m := range h.middleware {
+		snap.Callbacks["Use"] = append(snap.Callbacks["Use"], funcName(m))
+	}
+	h.funcsLock.RUnlock()
+	return snap
+}
+
+func (h *Handle) state() State {
+	switch {
+	case h.stopped():
+		select {
+		case <-h.interruptChan:
+			return StateInterrupted
+		default:
+			return StateStopped
+		}
+	case !h.started():
+		return StateIdle
+	}
+	h.pauseLock.Lock()
+	defer h.pauseLock.Unlock()
+	if h.resumeChan != nil {
+		return StatePaused
+	}
+	return StateRunning
+}
+
+func funcName(f any) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+// RestoreHandle recreates a stopped Handle with the stats and errors recorded
+// in snap, for example after a process restart. Registered callbacks are not
+// restored, since only their names are known.
+func RestoreHandle(snap HandleSnapshot, callback func(string) error) (*Handle, error) {
+	if callback == nil {
+		return nil, errors.New("wave: RestoreHandle requires a callback")
+	}
+	if snap.Waves < 0 || snap.Processed < 0 || snap.ErrorCount < 0 {
+		return nil, errors.New("wave: snapshot has negative counters")
+	}
+	h := newHandle(snap.Concurrency, func() Source { return SliceSource(nil) }, callback)
+	h.id = snap.WaveID
+	h.name = snap.Name
+	h.startTime = snap.StartTime
+	h.lastWaveTime = snap.LastWaveCompletedAt
+	h.waves.Store(snap.Waves)
+	h.waveProcessed.Store(snap.ProcessedInWave)
+	h.processed.Store(snap.Processed)
+	h.errorCount.Store(snap.ErrorCount)
+	for _, e := range snap.Errors {
+		h.errors = append(h.errors, ItemError{Val: e.Val, Err: errors.New(e.Err)})
+	}
+	if snap.State == StateInterrupted {
+		h.interrupt.Do(func() { close(h.interruptChan) })
+	}
+	h.Start()
+	h.stop()
+	return h, nil
+}
+
+// String summarizes the wave for logging, for example
+// Handle{state:running, wave:3, processed:1420, concurrency:10}, starting
+// with name:pinger, if the wave has a name.
+func (h *Handle) String() string {
+	name := ""
+	if n := h.waveName(); n != "" {
+		name = "name:" + n + ", "
+	}
+	return fmt.Sprintf("Handle{%sstate:%s, wave:%d, processed:%d, concurrency:%d}",
+		name, h.state(), h.waves.Load(), h.processed.Load(), h.concurrency)
+}
+
+// MarshalJSON encodes a summary of the wave for monitoring endpoints. Use
+// Snapshot for the full state.
+func (h *Handle) MarshalJSON() ([]byte, error) {
+	snap := h.Snapshot()
+	v := struct {
+		State          State   `json:"state"`
+		WaveNumber     int64   `json:"wave_number"`
+		ProcessedCount int64   `json:"processed_count"`
+		ErrorCount     int64   `json:"error_count"`
+		Concurrency    int     `json:"concurrency"`
+		StartTime      string  `json:"start_time,omitempty"` // RFC 3339, omitted if not started
+		ElapsedSeconds float64 `json:"elapsed_seconds"`      // Up to the last wave if stopped
+	}{
+		State:          snap.State,
+		WaveNumber:     snap.Waves,
+		ProcessedCount: snap.Processed,
+		ErrorCount:     snap.ErrorCount,
+		Concurrency:    snap.Concurrency,
+	}
+	if !snap.StartTime.IsZero() {
+		v.StartTime = snap.StartTime.Format(time.RFC3339)
+		end := time.Now()
+		if (snap.State == StateStopped || snap.State == StateInterrupted) && !snap.LastWaveCompletedAt.IsZero() {
+			end = snap.LastWaveCompletedAt
+		}
+		v.ElapsedSeconds = end.Sub(snap.StartTime).Seconds()
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,163 @@
+package wave
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	w := once(3, SliceSource(FakeEndpoints()), func(host string) error {
+		if host == ":3001" {
+			return errFault
+		}
+		return nil
+	})
+	w.AfterEach(func() {})
+	w.Finish()
+
+	data, err := json.Marshal(w.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap HandleSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Processed != numPorts || snap.ErrorCount != 1 || snap.Waves != 1 || snap.State != StateStopped {
+		t.Errorf("Unexpected snapshot %+v", snap)
+	}
+	if names := snap.Callbacks["AfterEach"]; len(names) != 1 || !strings.Contains(names[0], "TestSnapshotRestore") {
+		t.Error("Unexpected callback names", names)
+	}
+
+	r, err := RestoreHandle(snap, func(string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait() // Restored handles are stopped
+	restored := r.Snapshot()
+	if restored.Processed != snap.Processed || restored.WaveID != snap.WaveID || restored.Concurrency != 3 {
+		t.Errorf("Unexpected restored snapshot %+v", restored)
+	}
+	if errs := r.Errors(); len(errs) != 1 || errs[0].Val != ":3001" || errs[0].Err.Error() != errFault.Error() {
+		t.Error("Unexpected restored errors", errs)
+	}
+
+	if _, err := RestoreHandle(snap, nil); err == nil {
+		t.Error("Expected error without a callback")
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	w := Once(2, FakeEndpoints(), func(string) {
+		<-release
+	})
+	if state := w.Snapshot().State; state != StateIdle {
+		t.Error("Expected idle, got", state)
+	}
+	w.Start()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			snap := w.Snapshot()
+			if snap.State == StateIdle || snap.Processed > numPorts {
+				t.Errorf("Unexpected snapshot %+v", snap)
+			}
+		}
+	}()
+	<-done
+	close(release)
+	w.Wait()
+
+	snap := w.Snapshot()
+	if snap.State != StateStopped || snap.ProcessedInWave != numPorts || snap.QueueDepth != 0 {
+		t.Errorf("Unexpected snapshot %+v", snap)
+	}
+	if snap.StartTime.IsZero() || snap.LastWaveCompletedAt.Before(snap.StartTime) {
+		t.Errorf("Unexpected times %+v", snap)
+	}
+
+	i := Once(1, FakeEndpoints(), func(string) {})
+	i.Start()
+	i.Interrupt()
+	if state := i.Snapshot().State; state != StateInterrupted {
+		t.Error("Expected interrupted, got", state)
+	}
+}
+
+func TestHandleString(t *testing.T) {
+	w := Once(4, FakeEndpoints(), func(string) {})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = w.String()
+		}
+	}()
+	w.Finish()
+	<-done
+
+	s := fmt.Sprint(w)
+	for _, want := range []string{"Handle{", "state:stopped", "wave:1", "processed:10", "concurrency:4"} {
+		if !strings.Contains(s, want) {
+			t.Error("Expected", want, "in", s)
+		}
+	}
+}
+
+func TestHandleMarshalJSON(t *testing.T) {
+	w := Once(3, FakeEndpoints(), func(string) { time.Sleep(time.Millisecond) })
+	w.Finish()
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["state"] != "stopped" {
+		t.Error("Expected state stopped, got", m["state"])
+	}
+	for key, want := range map[string]float64{"wave_number": 1, "processed_count": numPorts, "error_count": 0, "concurrency": 3} {
+		if got, ok := m[key].(float64); !ok || got != want {
+			t.Error("Expected", key, want, "got", m[key])
+		}
+	}
+	start, ok := m["start_time"].(string)
+	if !ok {
+		t.Fatal("Expected start_time string, got", m["start_time"])
+	}
+	if _, err := time.Parse(time.RFC3339, start); err != nil {
+		t.Error(err)
+	}
+	if elapsed, ok := m["elapsed_seconds"].(float64); !ok || elapsed <= 0 {
+		t.Error("Expected positive elapsed_seconds, got", m["elapsed_seconds"])
+	}
+}
+
+func TestHandleMarshalJSONIdle(t *testing.T) {
+	w := Once(1, FakeEndpoints(), func(string) {})
+	data, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["state"] != "idle" {
+		t.Error("Expected state idle, got", m["state"])
+	}
+	if _, ok := m["start_time"]; ok {
+		t.Error("Expected no start_time before Start, got", m["start_time"])
+	}
+	w.Finish()
+}
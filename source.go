@@ -0,0 +1,179 @@
+package wave
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Source supplies the items of a wave one at a time, so that they can be
+// loaded lazily instead of being held in a slice up front. Next returns false
+// once the source is exhausted.
+type Source interface {
+	Next() (string, bool)
+}
+
+// Resetter is implemented by sources that can start over from their first
+// item. ContinuousFromSource resets its source before every wave but the
+// first.
+type Resetter interface {
+	Reset() error
+}
+
+// ErrSource is implemented by sources whose reads can fail, such as the ones
+// returned by FileSource. A failed read ends the source early, and Err then
+// reports the error. It should be checked after the wave completes.
+type ErrSource interface {
+	Source
+	Err() error
+}
+
+// failedSource is the source of a wave whose items could not be produced. It
+// has no items, and the wave records err and finishes.
+type failedSource struct {
+	err error
+}
+
+func (s *failedSource) Next() (string, bool) {
+	return "", false
+}
+
+// sourceError is recorded for a wave whose source failed, so that it can be
+// told apart from the errors of items.
+type sourceError struct {
+	err error
+}
+
+func (e *sourceError) Error() string { return e.err.Error() }
+func (e *sourceError) Unwrap() error { return e.err }
+
+type sliceSource struct {
+	vals []string
+	next int
+}
+
+// SliceSource returns a Source that yields the strings in vals in order.
+func SliceSource(vals []string) Source {
+	return &sliceSource{vals: vals}
+}
+
+func (s *sliceSource) Next() (string, bool) {
+	if s.next >= len(s.vals) {
+		return "", false
+	}
+	s.next++
+	return s.vals[s.next-1], true
+}
+
+func (s *sliceSource) Reset() error {
+	s.next = 0
+	return nil
+}
+
+// interruptibleSource is implemented by sources whose Next may block, so that
+// an interrupted wave does not wait for the next item.
+type interruptibleSource interface {
+	nextOrStop(stop <-chan struct{}) (string, bool)
+}
+
+type chanSource <-chan string
+
+// ChannelSource returns a Source that yields strings received from ch until
+// it is closed.
+func ChannelSource(ch <-chan string) Source {
+	return chanSource(ch)
+}
+
+func (s chanSource) Next() (string, bool) {
+	val, ok := <-s
+	return val, ok
+}
+
+func (s chanSource) nextOrStop(stop <-chan struct{}) (string, bool) {
+	select {
+	case val, ok := <-s:
+		return val, ok
+	case <-stop:
+		return "", false
+	}
+}
+
+type funcSource func() (string, bool)
+
+// FuncSource returns a Source that calls f for every item.
+func FuncSource(f func() (string, bool)) Source {
+	return funcSource(f)
+}
+
+func (s funcSource) Next() (string, bool) {
+	return s()
+}
+
+type fileSource struct {
+	path    string
+	filter  func(string) bool
+	file    *os.File
+	scanner *bufio.Scanner
+	err     error
+}
+
+// FileSource returns a Source that streams the lines of a file, skipping blank
+// lines and lines starting with #. The file is read incrementally and closed
+// once the last line has been returned. Continuous waves reopen it before
+// every wave, so it can be edited between waves.
+// Read errors end the wave early and are reported by the Err method of the
+// returned ErrSource, which should be checked after the wave completes. The
+// returned source also implements Resetter.
+func FileSource(path string) (ErrSource, error) {
+	return FileSourceWithFilter(path, nil)
+}
+
+// FileSourceWithFilter is like FileSource, but additionally skips lines for
+// which filter returns false.
+func FileSourceWithFilter(path string, filter func(string) bool) (ErrSource, error) {
+	s := &fileSource{path: path, filter: filter}
+	if err := s.Reset(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSource) Next() (string, bool) {
+	if s.file == nil {
+		return "", false
+	}
+	for s.scanner.Scan() {
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if s.filter != nil && !s.filter(line) {
+			continue
+		}
+		return line, true
+	}
+	s.err = s.scanner.Err()
+	s.file.Close()
+	s.file = nil
+	return "", false
+}
+
+// Reset reopens the file from the beginning.
+func (s *fileSource) Reset() error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	file, err := os.Open(s.path)
+	if err != nil {
+		s.err = err
+		return err
+	}
+	s.file, s.scanner, s.err = file, bufio.NewScanner(file), nil
+	return nil
+}
+
+// Err returns the error that ended the last read of the file, if any.
+func (s *fileSource) Err() error {
+	return s.err
+}
@@ -0,0 +1,234 @@
+package wave
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSliceSource(t *testing.T) {
+	src := SliceSource([]string{"a", "b"})
+	for _, want := range []string{"a", "b"} {
+		if val, ok := src.Next(); !ok || val != want {
+			t.Error("Expected", want, "got", val, ok)
+		}
+	}
+	if _, ok := src.Next(); ok {
+		t.Error("Expected exhausted source")
+	}
+}
+
+func TestOnceFromChannelAndFuncSource(t *testing.T) {
+	ch := make(chan string, numPorts)
+	for _, host := range FakeEndpoints() {
+		ch <- host
+	}
+	close(ch)
+
+	i := 0
+	funcSrc := FuncSource(func() (string, bool) {
+		i++
+		return strconv.Itoa(i), i <= numPorts
+	})
+
+	for _, src := range []Source{ChannelSource(ch), funcSrc} {
+		var count atomic.Int32
+		OnceFromSource(3, src, func(string) {
+			count.Add(1)
+		}).Finish()
+		if n := count.Load(); n != numPorts {
+			t.Error("Expected", numPorts, "got", n)
+		}
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	const lines = 5000
+	filename := filepath.Join(t.TempDir(), "hosts")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bufio.NewWriter(file)
+	for i := 0; i < lines; i++ {
+		buf.WriteString("server-" + strconv.Itoa(i) + ".internal\n")
+	}
+	buf.Flush()
+	file.Close()
+
+	src, err := FileSource(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count atomic.Int32
+	OnceFromSource(10, src, func(string) {
+		count.Add(1)
+	}).Finish()
+	if n := count.Load(); n != lines {
+		t.Error("Expected", lines, "got", n)
+	}
+	if err := src.Err(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := FileSource(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestOnceFromChanClosedMidWave(t *testing.T) {
+	ch := make(chan string)
+	var count atomic.Int32
+	w := OnceFromChan(3, ch, func(string) {
+		count.Add(1)
+	})
+	w.Start()
+	for _, host := range FakeEndpoints() {
+		ch <- host
+	}
+	close(ch)
+	w.Wait()
+
+	if n := count.Load(); n != numPorts {
+		t.Error("Expected", numPorts, "got", n)
+	}
+}
+
+func TestOnceFromChanInterrupt(t *testing.T) {
+	ch := make(chan string, numPorts)
+	ch <- "first"
+	started := make(chan struct{})
+	w := OnceFromChan(1, ch, func(string) {
+		close(started)
+	})
+	w.Start()
+	<-started
+	w.Interrupt() // Would block forever if the wave waited for ch to close
+
+	for _, host := range FakeEndpoints() {
+		ch <- host
+	}
+	if n := len(ch); n != numPorts {
+		t.Error("Expected", numPorts, "items left in channel, got", n)
+	}
+}
+
+func TestContinuousFromChan(t *testing.T) {
+	var waves atomic.Int32
+	var count atomic.Int32
+	chans := func() <-chan string {
+		waves.Add(1)
+		ch := make(chan string, numPorts)
+		for _, host := range FakeEndpoints() {
+			ch <- host
+		}
+		close(ch)
+		return ch
+	}
+
+	w := ContinuousFromChan(3, chans, func(string) {
+		count.Add(1)
+	})
+	w.AfterEach(func() {
+		if waves.Load() == 3 {
+			go w.Finish() // Lets the current wave complete
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	if n := count.Load(); n != numPorts*waves.Load() {
+		t.Error("Expected", numPorts*waves.Load(), "got", n)
+	}
+}
+
+func TestFileSourceSkipsAndRereads(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "hosts")
+	write := func(content string) {
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# web tier\nweb-1\n\nweb-2\n  # db tier\ndb-1\n")
+
+	src, err := FileSourceWithFilter(filename, func(host string) bool {
+		return host != "db-1"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var waves [][]string
+	lock := sync.Mutex{}
+	var current []string
+	var w *Handle
+	w = ContinuousFromSource(1, src, func(host string) {
+		lock.Lock()
+		current = append(current, host)
+		lock.Unlock()
+	})
+	w.AfterEach(func() {
+		lock.Lock()
+		defer lock.Unlock()
+		waves = append(waves, current)
+		current = nil
+		switch len(waves) {
+		case 1:
+			write("web-3\n")
+		case 2:
+			go w.Finish()
+		}
+	})
+	w.Start()
+	w.Wait()
+
+	if got := strings.Join(waves[0], ","); got != "web-1,web-2" {
+		t.Error("Unexpected first wave", got)
+	}
+	if got := strings.Join(waves[1], ","); got != "web-3" {
+		t.Error("Unexpected second wave", got)
+	}
+	if err := src.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestContinuousFromSourceResetError(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(filename, []byte("web-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := FileSource(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int32
+	w := ContinuousFromSource(1, src, func(string) {
+		calls.Add(1)
+		os.Remove(filename) // Make the next Reset fail
+	})
+	w.Start()
+	if !w.waitTimeout(5 * time.Second) {
+		w.Interrupt()
+		t.Fatal("Expected the wave to finish when the source cannot be reset")
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Error("Expected only the first wave to process items, got", n)
+	}
+	errs := w.Errors()
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrNotExist) {
+		t.Error("Expected the reset error, got", errs)
+	}
+	if s := w.Snapshot().State; s != StateStopped {
+		t.Error("Expected the wave to finish, got", s)
+	}
+}
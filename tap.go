@@ -0,0 +1,170 @@
+package wave
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tapQueueSize is how many items may wait for the taps before further items
+// are dropped.
+const tapQueueSize = 1024
+
+// tapEvent is the result of an item, queued for the taps.
+type tapEvent struct {
+	val string
+	err error
+	dur time.Duration
+}
+
+// tapDispatcher passes queued items to the taps of a wave from a single
+// goroutine, so that workers never wait for taps.
+type tapDispatcher struct {
+	events  chan tapEvent // Created along with the dispatching goroutine
+	done    chan struct{} // Closed when the dispatching goroutine exits
+	closed  bool          // Set by flushTaps
+	start   sync.Once
+	lock    sync.RWMutex // Guards events and closed
+	dropped atomic.Int64
+}
+
+// tapRunner calls one tap, one item at a time, in its own goroutine, so that
+// the dispatcher can abandon a call that takes too long.
+type tapRunner struct {
+	fn        func(string, error, time.Duration)
+	in        chan tapEvent
+	done      chan struct{} // Receives once per finished call
+	abandoned bool          // A call timed out and has not finished yet
+}
+
+// Tap registers an observer that is passed each item, its error and how long
+// its callback took, after the callback returns. Unlike AfterEach, Tap may be
+// called while the wave is running and takes effect from the next item.
+// Workers do not wait for taps: items are queued and passed to the taps in
+// order by a single goroutine. Items are dropped, and counted by TapDropped,
+// if the queue is full or a tap is still busy with an item it was abandoned on
+// after the tap timeout; see SetTapTimeout. The wave stops once the taps have
+// seen the queued items.
+func (h *Handle) Tap(fn func(val string, err error, dur time.Duration)) {
+	h.funcsLock.Lock()
+	h.tapFuncs = append(h.tapFuncs, fn)
+	h.funcsLock.Unlock()
+}
+
+// SetTapTimeout sets how long a tap may take for an item before it is
+// abandoned and passed no more items until it returns. The default is one
+// second. Zero or less means taps are never abandoned.
+func (h *Handle) SetTapTimeout(d time.Duration) {
+	h.funcsLock.Lock()
+	h.tapTimeout = d
+	h.funcsLock.Unlock()
+}
+
+// TapDropped returns how many items were not passed to a tap because the tap
+// queue was full or the tap was still busy after the tap timeout. An item
+// dropped for several taps counts once for each.
+func (h *Handle) TapDropped() int64 {
+	return h.taps.dropped.Load()
+}
+
+// tap queues the result of an item for the taps without blocking.
+func (h *Handle) tap(val string, err error, dur time.Duration) {
+	h.funcsLock.RLock()
+	n := len(h.tapFuncs)
+	h.funcsLock.RUnlock()
+	if n == 0 {
+		return
+	}
+	t := &h.taps
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.closed {
+		return
+	}
+	t.start.Do(func() {
+		t.events, t.done = make(chan tapEvent, tapQueueSize), make(chan struct{})
+		go h.dispatchTaps(t.events, t.done)
+	})
+	select {
+	case t.events <- tapEvent{val: val, err: err, dur: dur}:
+	default:
+		t.dropped.Add(int64(n))
+	}
+}
+
+// flushTaps waits until the queued items have been passed to the taps and
+// stops the dispatching goroutine. Later items are not passed to the taps.
+func (h *Handle) flushTaps() {
+	t := &h.taps
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return
+	}
+	t.closed = true
+	events, done := t.events, t.done
+	t.lock.Unlock()
+	if events != nil {
+		close(events)
+		<-done
+	}
+}
+
+func (h *Handle) dispatchTaps(events <-chan tapEvent, done chan<- struct{}) {
+	defer close(done)
+	var runners []*tapRunner
+	defer func() {
+		for _, r := range runners {
+			close(r.in)
+		}
+	}()
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for ev := range events {
+		h.funcsLock.RLock()
+		for _, fn := range h.tapFuncs[len(runners):] {
+			r := &tapRunner{fn: fn, in: make(chan tapEvent), done: make(chan struct{}, 1)}
+			go r.run()
+			runners = append(runners, r)
+		}
+		timeout := h.tapTimeout
+		h.funcsLock.RUnlock()
+
+		for _, r := range runners {
+			if r.abandoned {
+				select {
+				case <-r.done:
+					r.abandoned = false
+				default:
+					h.taps.dropped.Add(1)
+					continue
+				}
+			}
+			r.in <- ev
+			if timeout <= 0 {
+				<-r.done
+				continue
+			}
+			timer.Reset(timeout)
+			select {
+			case <-r.done:
+				if !timer.Stop() {
+					select { // Drain a stale expiry before the next Reset
+					case <-timer.C:
+					default:
+					}
+				}
+			case <-timer.C:
+				r.abandoned = true
+			}
+		}
+	}
+}
+
+func (r *tapRunner) run() {
+	for ev := range r.in {
+		r.fn(ev.val, ev.err, ev.dur)
+		r.done <- struct{}{}
+	}
+}
@@ -0,0 +1,104 @@
+package wave
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTap(t *testing.T) {
+	var tapped atomic.Int32
+	w := Once(10, FakeEndpoints(), func(host string) {})
+	w.Tap(func(val string, err error, dur time.Duration) {
+		if err != nil {
+			t.Error("Unexpected error", err)
+		}
+		tapped.Add(1)
+	})
+	w.Finish()
+
+	if n := tapped.Load(); n != numPorts {
+		t.Error("Expected", numPorts, "got", n)
+	}
+}
+
+func TestTapDoesNotBlockWorkers(t *testing.T) {
+	release := make(chan struct{})
+	w := Once(2, FakeEndpoints(), func(string) {})
+	w.Tap(func(string, error, time.Duration) { <-release })
+	w.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for w.CompletedCount() != numPorts && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := w.CompletedCount(); n != numPorts {
+		t.Error("Expected the workers to finish every item while the tap is blocked, got", n)
+	}
+	close(release)
+	w.Wait()
+}
+
+func TestTapTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	var calls atomic.Int32
+	w := OnceWithOptions(FakeEndpoints(), func(host string) {}, WithTapTimeout(time.Millisecond))
+	w.Tap(func(string, error, time.Duration) {
+		calls.Add(1)
+		<-block
+	})
+
+	done := make(chan struct{})
+	go func() {
+		w.Finish()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Slow tap blocked the wave")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Error("Expected the abandoned tap to get no more items, got", n, "calls")
+	}
+	if n := w.TapDropped(); n != numPorts-1 {
+		t.Error("Expected", numPorts-1, "dropped items, got", n)
+	}
+}
+
+func TestTapQueueFull(t *testing.T) {
+	items := make([]string, tapQueueSize+100)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+	release := make(chan struct{})
+	var tapped atomic.Int64
+	w := Once(4, items, func(string) {})
+	w.SetTapTimeout(0)
+	w.Tap(func(string, error, time.Duration) {
+		<-release
+		tapped.Add(1)
+	})
+	w.Start()
+
+	// The blocked tap holds at most one item and the queue tapQueueSize, so
+	// wait until the rest has been dropped. CompletedCount is not enough, since
+	// items are counted before they are queued for the taps.
+	wantDropped := int64(len(items) - tapQueueSize - 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for w.TapDropped() < wantDropped && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	w.Wait()
+
+	if n := tapped.Load() + w.TapDropped(); n != int64(len(items)) {
+		t.Error("Expected every item to be tapped or dropped, got", n)
+	}
+	if n := w.TapDropped(); n < wantDropped {
+		t.Error("Expected the items beyond the queue to be dropped, got", n)
+	}
+}
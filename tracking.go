@@ -0,0 +1,83 @@
+package wave
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ItemStat is the callback duration of an item, as returned by SlowItems.
+type ItemStat struct {
+	Item     string
+	Duration time.Duration
+}
+
+// itemTracker keeps the slowest items of a wave in a min-heap, so that the
+// fastest of them is the one replaced by a slower item.
+type itemTracker struct {
+	topN  int
+	items statHeap
+	lock  sync.Mutex // Guards all fields
+}
+
+// SetItemTracking makes the wave remember its topN slowest items, measured by
+// the duration of the callback chain, for SlowItems. A topN of 0 disables
+// tracking.
+func (h *Handle) SetItemTracking(topN int) {
+	h.tracker.lock.Lock()
+	h.tracker.topN = max(topN, 0)
+	h.tracker.items = nil
+	h.tracker.lock.Unlock()
+}
+
+// SlowItems returns the slowest items of the current or most recent wave,
+// slowest first. It returns nil unless SetItemTracking was called.
+func (h *Handle) SlowItems() []ItemStat {
+	h.tracker.lock.Lock()
+	stats := slices.Clone(h.tracker.items)
+	h.tracker.lock.Unlock()
+	slices.SortFunc(stats, func(a, b ItemStat) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	return stats
+}
+
+func (h *Handle) trackItem(val string, d time.Duration) {
+	t := &h.tracker
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	switch {
+	case t.topN == 0:
+	case len(t.items) < t.topN:
+		heap.Push(&t.items, ItemStat{Item: val, Duration: d})
+	case d > t.items[0].Duration:
+		t.items[0] = ItemStat{Item: val, Duration: d}
+		heap.Fix(&t.items, 0)
+	}
+}
+
+func (h *Handle) resetItemTracking() {
+	h.tracker.lock.Lock()
+	h.tracker.items = nil
+	h.tracker.lock.Unlock()
+}
+
+// statHeap is a heap.Interface of ItemStats with the fastest first.
+type statHeap []ItemStat
+
+func (s statHeap) Len() int           { return len(s) }
+func (s statHeap) Less(i, j int) bool { return s[i].Duration < s[j].Duration }
+func (s statHeap) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (s *statHeap) Push(x any) {
+	*s = append(*s, x.(ItemStat))
+}
+
+func (s *statHeap) Pop() any {
+	old := *s
+	x := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,38 @@
+package wave
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowItems(t *testing.T) {
+	slow := map[string]bool{":3002": true, ":3005": true, ":3007": true}
+	w := Once(numPorts, FakeEndpoints(), func(val string) {
+		if slow[val] {
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+	w.SetItemTracking(3)
+	w.Finish()
+
+	stats := w.SlowItems()
+	if len(stats) != 3 {
+		t.Fatal("Expected 3 slow items, got", stats)
+	}
+	for i, s := range stats {
+		if !slow[s.Item] {
+			t.Error("Expected", s.Item, "not to be among the slowest")
+		}
+		if i > 0 && s.Duration > stats[i-1].Duration {
+			t.Error("Expected slowest first, got", stats)
+		}
+	}
+}
+
+func TestSlowItemsDisabled(t *testing.T) {
+	w := Once(2, FakeEndpoints(), func(string) {})
+	w.Finish()
+	if stats := w.SlowItems(); len(stats) != 0 {
+		t.Error("Expected no slow items without tracking, got", stats)
+	}
+}
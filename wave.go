@@ -5,7 +5,15 @@
 package wave
 
 import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Once prepares a wave that will only execute one time. Call Start, Wait, or
@@ -15,15 +23,67 @@ import (
 // strings in the vals slice. For remote monitoring, this would probably be a
 // hostname.
 func Once(concurrency int, vals []string, callback func(string)) *Handle {
-	h := newHandle()
-	go func() {
-		<-h.startChan
-		doTheWave(concurrency, vals, callback, h)
-		close(h.stopChan)
-	}()
+	return OnceWithOptions(vals, callback, WithConcurrency(concurrency))
+}
+
+// OnceFromSource is like Once, but takes its items from src as the wave
+// progresses instead of from a slice.
+func OnceFromSource(concurrency int, src Source, callback func(string)) *Handle {
+	return once(concurrency, src, noError(callback))
+}
+
+// OnceFromChan is like Once, but takes its items from ch. The wave finishes
+// once ch is closed and the received items have been processed. Items still
+// in ch are left there if the wave is interrupted.
+func OnceFromChan(concurrency int, ch <-chan string, callback func(string)) *Handle {
+	return once(concurrency, ChannelSource(ch), noError(callback))
+}
+
+func once(concurrency int, src Source, callback func(string) error) *Handle {
+	h := newHandle(concurrency, func() Source { return src }, callback)
+	h.recoverable = true
+	go h.runOnce()
 	return h
 }
 
+func (h *Handle) runOnce() {
+	<-h.startChan
+	h.debug("receive", "startChan")
+	if h.prev != nil && !h.waitForPrev() {
+		h.stop()
+		return
+	}
+	pool := h.newPool()
+	for {
+		if panicked, restart := h.runWave(pool); !panicked || !restart {
+			break
+		}
+	}
+	pool.Close()
+	h.stop()
+}
+
+// waitForPrev waits for the previous pipeline stage to stop, passing Interrupt
+// and Finish on to it. It returns false if h was interrupted, in which case
+// the previous stage has stopped too and h should not run.
+func (h *Handle) waitForPrev() bool {
+	finish := h.finishChan
+	for {
+		select {
+		case <-h.prev.stopChan:
+			return true
+		case <-h.interruptChan:
+			h.debug("receive", "interruptChan")
+			h.prev.Interrupt()
+			return false
+		case <-finish:
+			h.debug("receive", "finishChan")
+			finish = nil
+			go h.prev.Finish()
+		}
+	}
+}
+
 // Continuous prepares a wave that will automatically repeat unless stopped
 // by a call to Interrupt or Finish on the returned handle. Call Start, Wait, or
 // Finish on the returned handle to start the wave.
@@ -32,59 +92,279 @@ func Once(concurrency int, vals []string, callback func(string)) *Handle {
 // strings in the vals slice. For remote monitoring, this would probably be a
 // hostname.
 func Continuous(concurrency int, vals []string, callback func(string)) *Handle {
-	h := newHandle()
-	go func() {
-		<-h.startChan
-		first := true
-	loop:
-		for {
-			select {
-			case <-h.interruptChan:
+	return ContinuousWithOptions(vals, callback, WithConcurrency(concurrency))
+}
+
+// ContinuousFromChan is like Continuous, but takes the items of each wave
+// from a channel. Since a channel can only be drained once, chans is called
+// before every wave to produce a fresh channel, and the wave ends when that
+// channel is closed.
+func ContinuousFromChan(concurrency int, chans func() <-chan string, callback func(string)) *Handle {
+	return continuous(concurrency, func() Source { return ChannelSource(chans()) }, noError(callback))
+}
+
+// ContinuousFromSource is like Continuous, but takes its items from src. If
+// src implements Resetter, it is reset before every wave after the first;
+// otherwise later waves only see items that src produces after the first wave
+// exhausted it. If Reset fails, the wave finishes without processing any
+// items, and Errors reports the error with an empty Val.
+func ContinuousFromSource(concurrency int, src Source, callback func(string)) *Handle {
+	first := true
+	return continuous(concurrency, func() Source {
+		if r, ok := src.(Resetter); ok && !first {
+			if err := r.Reset(); err != nil {
+				return &failedSource{err: fmt.Errorf("wave: resetting the source: %w", err)}
+			}
+		}
+		first = false
+		return src
+	}, noError(callback))
+}
+
+// continuous runs waves over a new source from newSource until stopped.
+func continuous(concurrency int, newSource func() Source, callback func(string) error) *Handle {
+	h := newHandle(concurrency, newSource, callback)
+	h.continuous = true
+	h.recoverable = true
+	go h.runContinuous()
+	return h
+}
+
+func (h *Handle) runContinuous() {
+	<-h.startChan
+	h.debug("receive", "startChan")
+	pool := h.newPool()
+	first := true
+loop:
+	for {
+		select {
+		case <-h.interruptChan:
+			h.debug("receive", "interruptChan")
+			break loop
+		case <-h.finishChan:
+			h.debug("receive", "finishChan")
+			if first {
+				h.runWave(pool)
+				first = false
+			}
+			break loop
+		default:
+			first = false
+			if !h.waitForCircuit() {
+				continue // Interrupted or finished while the circuit was open
+			}
+			waves := h.waves.Load()
+			if panicked, restart := h.runWave(pool); panicked && !restart {
 				break loop
-			case <-h.finishChan:
-				if first {
-					doTheWave(concurrency, vals, callback, h)
-					first = false
-				}
+			}
+			if h.waves.Load() == waves && !h.sleep(h.lockRetry) {
+				continue // Skipped for the lock, then interrupted or finished
+			}
+			if h.maxWaves > 0 && h.waves.Load() >= int64(h.maxWaves) {
 				break loop
-			default:
-				doTheWave(concurrency, vals, callback, h)
-				first = false
 			}
 		}
-		close(h.stopChan)
-	}()
-	return h
+	}
+	pool.Close()
+	h.stop()
 }
 
-func doTheWave(concurrency int, vals []string, callback func(string), h *Handle) {
-	valChan := make(chan string, 10)
-	go func() {
-		for _, val := range vals {
-			valChan <- val
+const (
+	queueCapacity     = 10          // Items that may wait for a worker during a wave
+	defaultTapTimeout = time.Second // How long taps may take by default
+)
+
+// dispatcher hands the items of a wave to workers.
+type dispatcher interface {
+	SubmitBlocking(item string) error
+	wait()
+	Close() error
+}
+
+// newPool creates the workers that run the waves of h: a WorkerPool, or a
+// scheduledPool if a WorkerScheduler is set. The workers outlive individual
+// waves, so Continuous waves reuse them.
+func (h *Handle) newPool() dispatcher {
+	h.stateLock.Lock()
+	h.startTime = time.Now()
+	scheduler := h.scheduler
+	h.stateLock.Unlock()
+	if scheduler != nil {
+		return newScheduledPool(h.concurrency, scheduler, h.process)
+	}
+	pool := NewWorkerPool(h.CurrentConcurrency(), h.process)
+	if h.priority != nil {
+		pool.SetQueueCapacity(1) // Leave the ordering to the priority queue
+	} else {
+		pool.SetQueueCapacity(queueCapacity)
+	}
+	h.pool.Store(pool)
+	return pool
+}
+
+// process runs the callback chain for a single item.
+func (h *Handle) process(val string) {
+	h.debug("receive", "valChan", "item", val)
+	chain := h.chainFor(val) // Before any return, to settle the origin of interleaved items
+	h.waitIfPaused()
+	select {
+	case <-h.interruptChan:
+		// Skip the rest of the wave.
+	default:
+		if h.seenInWave(val) {
+			return
+		}
+		if h.dryRun.Load() {
+			h.dryRunCount.Add(1)
+			return
+		}
+		h.stateLock.RLock()
+		transform, store := h.transform, h.idempotency
+		h.stateLock.RUnlock()
+		if store != nil && !store.Claim(val) {
+			return
+		}
+		if !h.takeQuota() {
+			if store != nil {
+				store.Release(val)
+			}
+			return
+		}
+		h.emit(EventItemStarted, val, nil)
+		began := time.Now()
+		err := h.callItem(chain, transform, val)
+		h.processed.Add(1)
+		h.waveProcessed.Add(1)
+		if err != nil {
+			h.recordError(val, err)
+			h.emit(EventItemErrored, val, err)
+			h.deadLetter(val, err)
+			if store != nil {
+				store.Release(val)
+			}
+		} else {
+			h.emit(EventItemFinished, val, nil)
+			if store != nil {
+				h.claims.keep(store, val)
+			}
+		}
+		h.recordOutcome(err)
+		dur := time.Since(began)
+		h.tap(val, err, dur)
+		h.trackItem(val, dur)
+		if err == nil {
+			h.checkpointItem(val)
+		}
+		h.funcsLock.RLock()
+		for _, f := range h.pipeFuncs {
+			f(val)
+		}
+		h.funcsLock.RUnlock()
+	}
+}
+
+// call passes val through transform, if any, and then to chain. A panicking
+// transform is reported as the error of the item.
+func call(chain func(string) error, transform func(string) string, val string) error {
+	if transform != nil {
+		var err error
+		if val, err = transformed(transform, val); err != nil {
+			return err
+		}
+	}
+	return chain(val)
+}
+
+// transformed passes val through transform, recovering a panic as an error.
+func transformed(transform func(string) string, val string) (out string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("wave: transform of %q panicked: %v", val, r)
 		}
-		close(valChan)
 	}()
-	wg := sync.WaitGroup{}
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-h.interruptChan:
-					return
-				default:
-					val, ok := <-valChan
-					if !ok {
-						return
-					}
-					callback(val)
-				}
+	return transform(val), nil
+}
+
+// newWaveID returns a random 128-bit ID in hex.
+func newWaveID() string {
+	b := make([]byte, 16)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// noError adapts a callback that cannot fail to the callback chain.
+func noError(callback func(string)) func(string) error {
+	return func(val string) error {
+		callback(val)
+		return nil
+	}
+}
+
+func doTheWave(src Source, pool dispatcher, h *Handle) {
+	if !h.circuitAllows() {
+		h.debug("skip", "wave", "reason", "circuit open")
+		return
+	}
+	unlock, ok := h.lockWave()
+	if !ok {
+		return
+	}
+	defer unlock()
+	h.stateLock.Lock()
+	h.id = newWaveID()
+	h.stateLock.Unlock()
+	h.waveProcessed.Store(0)
+	h.dryRunCount.Store(0)
+	h.resetSeen()
+	h.waves.Add(1)
+	h.resetErrors()
+	h.resetItemTracking()
+	h.claims.release()
+	h.emit(EventWaveStarted, "", nil)
+	if fs, ok := src.(*failedSource); ok {
+		h.recordError("", &sourceError{fs.err})
+		h.finish.Do(func() {
+			h.debug("close", "finishChan", "reason", "source failed")
+			close(h.finishChan)
+		})
+	}
+
+	src = h.prepare(src)
+feed:
+	for {
+		select {
+		case <-h.interruptChan:
+			break feed
+		default:
+			var val string
+			var ok bool
+			if is, interruptible := src.(interruptibleSource); interruptible {
+				val, ok = is.nextOrStop(h.interruptChan)
+			} else {
+				val, ok = src.Next()
+			}
+			if !ok {
+				break feed
 			}
-		}()
+			h.debug("send", "valChan", "item", val)
+			pool.SubmitBlocking(val)
+		}
 	}
-	wg.Wait()
+	pool.wait()
+	h.flushCheckpoint()
+	h.updateCircuit()
+	if h.continuous {
+		h.adjustConcurrency()
+	}
+	select {
+	case <-h.interruptChan:
+		h.emit(EventWaveInterrupted, "", nil)
+	default:
+		h.emit(EventWaveFinished, "", nil)
+	}
+	h.stateLock.Lock()
+	h.lastWaveTime = time.Now()
+	h.stateLock.Unlock()
 	h.trigger(h.eachFuncs)
 }
 
@@ -96,20 +376,94 @@ type Handle struct {
 	interruptChan chan struct{} // Close to request interrupt
 	finishChan    chan struct{} // Close to request finish
 	stopChan      chan struct{} // Close when stopped
+	concurrency   int
+	newSource     func() Source // Returns the items of the next wave
+	prev          *Handle       // Pipeline stage that must stop before this one runs
+	callback      func(string) error
+	middleware    []CallbackMiddleware
+	chain         func(string) error // Middleware around callback, guarded by funcsLock
+	interleaved   *interleavedSource // Set by Interleave to route items to their origin
 	stopFuncs     []func()
 	eachFuncs     []func()
-	funcsLock     sync.RWMutex // Guards all []func()
+	tapFuncs      []func(string, error, time.Duration)
+	pipeFuncs     []func(string)
+	tapTimeout    time.Duration // Guarded by funcsLock
+	taps          tapDispatcher
+	funcsLock     sync.RWMutex  // Guards all []func()
+	resumeChan    chan struct{} // Non-nil while paused, closed on resume
+	pauseLock     sync.Mutex    // Guards resumeChan
+	id            string        // ID of the current wave
+	shuffle       bool
+	shuffleSeed   *int64
+	dedup         bool
+	duplicates    atomic.Int64 // Removed by dedup in the current wave
+	filter        func(string) bool
+	skipped       atomic.Int64 // Rejected by filter in the current wave
+	transform     func(string) string
+	waves         atomic.Int64 // Waves started
+	processed     atomic.Int64 // Items processed by all waves
+	waveProcessed atomic.Int64 // Items processed by the current wave
+	errorCount    atomic.Int64 // Items that failed in all waves
+	errors        []ItemError  // Of the current wave
+	errorsLock    sync.Mutex   // Guards errors and errorsWave
+	errorsCond    *sync.Cond   // Signalled when an error is recorded or the wave stops
+	errorsWave    int          // Incremented when errors is reset
+	name          string       // Guarded by stateLock
+	eventChans    []chan Event
+	eventsLock    sync.Mutex // Guards eventChans
+	pool          atomic.Pointer[WorkerPool]
+	startTime     time.Time
+	lastWaveTime  time.Time    // When the last wave completed
+	stateLock     sync.RWMutex // Guards wave state
+	rng           *rand.Rand   // Used by the feeding goroutine to shuffle
+	checkpoint    checkpointer
+	dlq           chan<- DeadLetter
+	dlqDropped    atomic.Int64 // Failed items that did not fit in dlq
+	limit         atomic.Int64 // Items all waves may process, 0 for no limit
+	limitUsed     atomic.Int64 // Items reserved under limit
+	tracker       itemTracker
+	continuous    bool // Runs waves back to back
+	recoverable   bool // Created by once or continuous, see Recover
+	scheduler     WorkerScheduler
+	priority      *prioritySource          // Set by OnceWithPriority
+	vals          atomic.Pointer[[]string] // Items of every wave, set by Once and Continuous
+	adaptive      adaptiveConcurrency
+	dryRun        atomic.Bool
+	dryRunCount   atomic.Int64    // Items counted instead of processed in the current wave
+	signalBuffer  atomic.Int64    // Signals queued by ContinuousOnSignal
+	maxWaves      int             // Waves a Continuous wave runs, 0 for no limit
+	timedOut      atomic.Bool     // Interrupted by WithTimeout
+	watchCtx      context.Context // Set by WithContext, interrupts the wave when done
+	watchTimeout  time.Duration   // Set by WithTimeout, measured from Start
+	idempotency   IdempotencyStore
+	claims        waveClaims          // Released when the next wave starts
+	debugLog      *slog.Logger        // Set by WithDebugMode
+	recovery      *panicRecovery      // Set by WithPanicRecovery
+	circuit       *waveCircuitBreaker // Set by WithWaveCircuitBreaker
+	lock          DistributedLock     // Set by WithDistributedLock
+	lockRetry     time.Duration       // How long a Continuous wave waits after losing lock
+	seen          map[string]struct{} // Items processed by the current wave, nil unless deduplicating
+	seenLock      sync.Mutex          // Guards seen
+	seenDups      atomic.Int64        // Items skipped because of seen in the current wave
 }
 
-func newHandle() *Handle {
-	return &Handle{
+func newHandle(concurrency int, newSource func() Source, callback func(string) error) *Handle {
+	h := &Handle{
+		concurrency:   concurrency,
+		newSource:     newSource,
+		callback:      callback,
+		chain:         callback,
 		startChan:     make(chan struct{}),
 		interruptChan: make(chan struct{}),
 		finishChan:    make(chan struct{}),
 		stopChan:      make(chan struct{}),
 		stopFuncs:     []func(){},
 		eachFuncs:     []func(){},
+		tapTimeout:    defaultTapTimeout,
+		lockRetry:     defaultLockRetry,
 	}
+	h.errorsCond = sync.NewCond(&h.errorsLock)
+	return h
 }
 
 func (h *Handle) trigger(fs []func()) {
@@ -123,9 +477,84 @@ func (h *Handle) trigger(fs []func()) {
 	wg.Wait()
 }
 
-// Start begins the wave.
+func (h *Handle) pause() {
+	h.pauseLock.Lock()
+	if h.resumeChan == nil {
+		h.resumeChan = make(chan struct{})
+	}
+	h.pauseLock.Unlock()
+}
+
+func (h *Handle) resume() {
+	h.pauseLock.Lock()
+	if h.resumeChan != nil {
+		close(h.resumeChan)
+		h.resumeChan = nil
+	}
+	h.pauseLock.Unlock()
+}
+
+// waitIfPaused blocks while the wave is paused, unless it gets interrupted.
+func (h *Handle) waitIfPaused() {
+	h.pauseLock.Lock()
+	resumeChan := h.resumeChan
+	h.pauseLock.Unlock()
+	if resumeChan != nil {
+		select {
+		case <-resumeChan:
+		case <-h.interruptChan:
+		}
+	}
+}
+
+// ID returns the unique ID of the current wave, or of the last one if the
+// wave has stopped. Continuous waves get a new ID for every repetition, which
+// can be used to correlate logs and metrics. It is empty before the wave
+// starts.
+func (h *Handle) ID() string {
+	h.stateLock.RLock()
+	defer h.stateLock.RUnlock()
+	return h.id
+}
+
+// QueuedCount returns the number of items of the current wave that are waiting
+// for a worker.
+func (h *Handle) QueuedCount() int {
+	if pool := h.pool.Load(); pool != nil {
+		return pool.QueueDepth()
+	}
+	return 0
+}
+
+// InFlightCount returns the number of items whose callback is running.
+func (h *Handle) InFlightCount() int {
+	if pool := h.pool.Load(); pool != nil {
+		return pool.inFlight()
+	}
+	return 0
+}
+
+// CompletedCount returns the number of items the current or most recent wave
+// has finished processing.
+func (h *Handle) CompletedCount() int {
+	return int(h.waveProcessed.Load())
+}
+
+// Start begins the wave. If the wave is a later stage of a pipeline, the
+// whole pipeline is started.
 func (h *Handle) Start() {
+	if h.prev != nil {
+		h.prev.Start()
+	}
 	h.start.Do(func() {
+		if h.watchCtx != nil && h.watchCtx.Err() != nil {
+			// Interrupt before starting, so that no items run.
+			h.interrupt.Do(func() {
+				h.debug("close", "interruptChan", "reason", "context done")
+				close(h.interruptChan)
+			})
+		}
+		h.debug("close", "startChan")
 		close(h.startChan)
 	})
 }
@@ -134,6 +563,7 @@ func (h *Handle) Start() {
 // continuing. It will block until all processing and callbacks have finished.
 func (h *Handle) Interrupt() {
 	h.interrupt.Do(func() {
+		h.debug("close", "interruptChan", "reason", "Interrupt")
 		close(h.interruptChan)
 	})
 	h.Wait()
@@ -146,17 +576,80 @@ func (h *Handle) Interrupt() {
 func (h *Handle) Finish() {
 	h.Start()
 	h.finish.Do(func() {
+		h.debug("close", "finishChan")
 		close(h.finishChan)
 	})
 	h.Wait()
 }
 
+// stop marks the wave as stopped and closes the channels returned by Events.
+func (h *Handle) stop() {
+	h.flushTaps()
+	h.eventsLock.Lock()
+	defer h.eventsLock.Unlock()
+	h.debug("close", "stopChan")
+	close(h.stopChan)
+	for _, ch := range h.eventChans {
+		close(ch)
+	}
+	h.eventChans = nil
+	h.errorsLock.Lock()
+	h.errorsCond.Broadcast()
+	h.errorsLock.Unlock()
+}
+
+func (h *Handle) started() bool {
+	select {
+	case <-h.startChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *Handle) stopped() bool {
+	select {
+	case <-h.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
 // Wait blocks until the wave has stopped.
 // For convenience, Wait also starts the wave if it hasn't started yet.
 func (h *Handle) Wait() {
 	<-h.stopChan
 }
 
+// RunWithDeadline starts the wave and blocks until it stops. If it is still
+// running at deadline, it is interrupted and context.DeadlineExceeded is
+// returned. Otherwise the errors of the last wave are returned, or nil.
+func (h *Handle) RunWithDeadline(deadline time.Time) error {
+	h.Start()
+	if !h.waitTimeout(time.Until(deadline)) {
+		h.Interrupt()
+		return context.DeadlineExceeded
+	}
+	if errs := h.Errors(); errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// waitTimeout is like Wait, but gives up after d. It reports whether the wave
+// stopped.
+func (h *Handle) waitTimeout(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-h.stopChan:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 // OnStop registers a function to be called after the wave has stopped.
 // Can be called multiple times to register multiple callbacks.
 func (h *Handle) OnStop(f func()) {
@@ -173,3 +666,27 @@ func (h *Handle) AfterEach(f func()) {
 	h.eachFuncs = append(h.eachFuncs, f)
 	h.funcsLock.Unlock()
 }
+
+// PauseOn polls condition every checkInterval until the wave stops. While
+// condition returns true, workers finish their current item and then wait
+// instead of picking up new ones. Processing resumes once condition returns
+// false again.
+func (h *Handle) PauseOn(condition func() bool, checkInterval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			if condition() {
+				h.pause()
+			} else {
+				h.resume()
+			}
+			select {
+			case <-ticker.C:
+			case <-h.stopChan:
+				h.resume()
+				return
+			}
+		}
+	}()
+}
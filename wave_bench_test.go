@@ -0,0 +1,28 @@
+package wave
+
+import (
+	"strconv"
+	"testing"
+)
+
+// benchmarkWave runs a wave of b.N items through callback, so that the
+// throughput of a callback can be measured with go test -bench.
+func benchmarkWave(b *testing.B, concurrency int, callback func(string)) {
+	vals := make([]string, b.N)
+	for i := range vals {
+		vals[i] = "item-" + strconv.Itoa(i)
+	}
+	w := Once(concurrency, vals, callback)
+	b.ResetTimer()
+	w.Finish()
+}
+
+func BenchmarkWaveNoop(b *testing.B) {
+	benchmarkWave(b, 10, func(string) {})
+}
+
+func BenchmarkWaveStrconv(b *testing.B) {
+	benchmarkWave(b, 10, func(val string) {
+		strconv.Quote(val)
+	})
+}
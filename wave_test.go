@@ -1,8 +1,12 @@
 package wave
 
 import (
+	"context"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 const (
@@ -98,3 +102,108 @@ func TestContinuousOnceFinish(t *testing.T) {
 
 	w.Finish()
 }
+
+func TestPauseOn(t *testing.T) {
+	var paused atomic.Bool
+	var count atomic.Int32
+	paused.Store(true)
+
+	w := Once(10, FakeEndpoints(), func(host string) {
+		count.Add(1)
+	})
+	w.PauseOn(paused.Load, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // Let the condition be polled
+	w.Start()
+
+	time.Sleep(20 * time.Millisecond)
+	if n := count.Load(); n != 0 {
+		t.Error("Expected 0 while paused, got", n)
+	}
+
+	paused.Store(false)
+	w.Wait()
+	if n := count.Load(); n != numPorts {
+		t.Error("Expected", numPorts, "got", n)
+	}
+}
+
+func TestID(t *testing.T) {
+	var ids []string
+	lock := sync.Mutex{}
+	var w *Handle
+	w = Continuous(10, FakeEndpoints(), func(host string) {})
+	w.AfterEach(func() {
+		lock.Lock()
+		defer lock.Unlock()
+		if ids = append(ids, w.ID()); len(ids) == 3 {
+			go w.Finish()
+		}
+	})
+	if id := w.ID(); id != "" {
+		t.Error("Expected no ID before start, got", id)
+	}
+	w.Start()
+	w.Wait()
+
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if len(id) != 32 || seen[id] {
+			t.Error("Expected a unique 32 character ID, got", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestRunWithDeadline(t *testing.T) {
+	w := once(2, SliceSource(FakeEndpoints()), func(host string) error {
+		if host == ":3005" {
+			return errFault
+		}
+		return nil
+	})
+	err := w.RunWithDeadline(time.Now().Add(time.Second))
+	if errs, ok := err.(MultiError); !ok || len(errs) != 1 || errs[0].Val != ":3005" {
+		t.Error("Expected the error of :3005, got", err)
+	}
+
+	if err := Once(2, FakeEndpoints(), func(string) {}).RunWithDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Error("Expected nil, got", err)
+	}
+
+	slow := Once(1, FakeEndpoints(), func(string) {
+		time.Sleep(10 * time.Millisecond)
+	})
+	began := time.Now()
+	if err := slow.RunWithDeadline(time.Now().Add(15 * time.Millisecond)); err != context.DeadlineExceeded {
+		t.Error("Expected context.DeadlineExceeded, got", err)
+	}
+	if d := time.Since(began); d > 50*time.Millisecond {
+		t.Error("Expected the wave to stop promptly, took", d)
+	}
+}
+
+func TestItemCounts(t *testing.T) {
+	release := make(chan struct{})
+	w := Once(2, FakeEndpoints(), func(string) { <-release })
+	w.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for w.InFlightCount() != 2 || w.QueuedCount() != numPorts-2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected 2 in flight and", numPorts-2, "queued, got", w.InFlightCount(), "and", w.QueuedCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := w.CompletedCount(); n != 0 {
+		t.Error("Expected 0 completed, got", n)
+	}
+
+	close(release)
+	w.Wait()
+	if n := w.CompletedCount(); n != numPorts {
+		t.Error("Expected", numPorts, "completed, got", n)
+	}
+	if n := w.QueuedCount() + w.InFlightCount(); n != 0 {
+		t.Error("Expected nothing queued or in flight, got", n)
+	}
+}